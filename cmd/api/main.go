@@ -1,18 +1,38 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/joho/godotenv"
+	"github.com/go-chi/cors"
+	"github.com/go-chi/httprate"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
+	"inventory-api/db"
+	"inventory-api/internal/auth"
+	"inventory-api/internal/config"
 	"inventory-api/internal/database"
 	"inventory-api/internal/handlers"
+	"inventory-api/internal/logging"
+	"inventory-api/internal/mailer"
+	"inventory-api/internal/metrics"
 	appMiddleware "inventory-api/internal/middleware"
 	"inventory-api/internal/repository"
+	"inventory-api/internal/sso"
+	"inventory-api/internal/tokens"
+	"inventory-api/internal/tracing"
 )
 
 func main() {
@@ -20,111 +40,578 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	slog.SetDefault(logger)
 
-	if err := godotenv.Load(); err != nil {
-		slog.Warn("File .env Not Found, Use Environment System")
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "optional YAML config file")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
+
+	// Semua setting dibaca sekali di sini; konfigurasi yang salah menghentikan startup
+	cfg, err := config.Load(*configFile)
+	if *printConfig {
+		out, yamlErr := cfg.YAML()
+		if yamlErr != nil {
+			fmt.Fprintln(os.Stderr, yamlErr)
+			os.Exit(1)
+		}
+		os.Stdout.Write(out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err != nil {
+		slog.Error("Could not load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Startup gagal kalau tidak ada signing key, supaya tidak pernah jalan dengan secret kosong
+	keySet, err := auth.LoadKeySet(cfg.JWT.KeysDir, cfg.JWT.ActiveKID)
+	if err != nil {
+		slog.Error("Could not load JWT signing keys", "error", err)
+		os.Exit(1)
 	}
 
-	connString := os.Getenv("DATABASE_URL")
-	port := os.Getenv("PORT")
+	tokenService := &auth.TokenService{
+		Keys:     keySet,
+		Issuer:   cfg.JWT.Issuer,
+		Audience: cfg.JWT.Audience,
+		TTL:      cfg.JWT.TTL,
+		Leeway:   cfg.JWT.Leeway,
+	}
 
-	if connString == "" || port == "" {
-		slog.Error("Config Environment Incomplete")
+	// Span hanya dikirim kalau OTEL_TRACES_EXPORTER=otlp; alamat collector lewat OTEL_EXPORTER_OTLP_ENDPOINT
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.ServiceName)
+	if err != nil {
+		slog.Error("Could not initialize tracing", "error", err)
 		os.Exit(1)
 	}
 
-	dbPool, err := database.InitDB(connString)
+	dbPool, err := database.InitDB(cfg.Database)
 	if err != nil {
-		slog.Error("Could not initialize database: %v", err)
+		slog.Error("Could not initialize database", "error", err)
 		os.Exit(1)
 	}
 	defer dbPool.Close()
 
+	// Listings and reports go to the replica when one is configured
+	var replicaPool *pgxpool.Pool
+	if cfg.Database.ReplicaURL != "" {
+		replicaConfig := cfg.Database
+		replicaConfig.URL = cfg.Database.ReplicaURL
+		replicaPool, err = database.InitDB(replicaConfig)
+		if err != nil {
+			slog.Error("Could not initialize read replica", "error", err)
+			os.Exit(1)
+		}
+		defer replicaPool.Close()
+	}
+
+	// Cross-tenant work runs as a BYPASSRLS role, since the tenant policies fail closed
+	systemPool := dbPool
+	if cfg.Database.SystemURL != "" {
+		systemConfig := cfg.Database
+		systemConfig.URL = cfg.Database.SystemURL
+		systemPool, err = database.InitDB(systemConfig)
+		if err != nil {
+			slog.Error("Could not initialize system database pool", "error", err)
+			os.Exit(1)
+		}
+		defer systemPool.Close()
+	}
+
 	productRepo := &repository.ProductRepository{
-		DB: dbPool,
+		DB:      dbPool,
+		Replica: replicaPool,
+		System:  systemPool,
+	}
+
+	stockMovementRepo := &repository.StockMovementRepository{
+		DB:      dbPool,
+		Replica: replicaPool,
+	}
+
+	// Serializable so concurrent units of work conflict with 40001 and are
+	// retried instead of interleaving
+	txManager := &repository.TxManager{
+		DB:       dbPool,
+		IsoLevel: pgx.Serializable,
 	}
 
 	productHandler := &handlers.ProductHandler{
-		Repo: productRepo,
+		Repo:      productRepo,
+		Movements: stockMovementRepo,
+		Tx:        txManager,
 	}
 
 	categoryRepo := &repository.CategoryRepository{
-		DB: dbPool,
+		DB:      dbPool,
+		Replica: replicaPool,
 	}
 
 	categoryHandler := &handlers.CategoryHandler{
-		Repo: categoryRepo,
+		Repo:     categoryRepo,
+		Products: productRepo,
+		Tx:       txManager,
 	}
 
 	customerRepo := &repository.CustomerRepository{
+		DB:      dbPool,
+		Replica: replicaPool,
+	}
+
+	customerAddressRepo := &repository.CustomerAddressRepository{
 		DB: dbPool,
 	}
 
 	customerHandler := &handlers.CustomerHandler{
-		Repo: customerRepo,
+		Repo:               customerRepo,
+		Addresses:          customerAddressRepo,
+		DefaultCountryCode: cfg.PhoneDefaultCountryCode,
 	}
 
 	userRepo := &repository.UserRepository{
 		DB: dbPool,
 	}
 
+	organizationRepo := &repository.OrganizationRepository{
+		DB: dbPool,
+	}
+
+	organizationHandler := &handlers.OrganizationHandler{
+		Repo: organizationRepo,
+	}
+
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		slog.Error("Could not initialize mailer", "error", err)
+		os.Exit(1)
+	}
+
+	baseURL := cfg.Server.BaseURL
+
+	invitationRepo := &repository.InvitationRepository{
+		DB:     dbPool,
+		System: systemPool,
+	}
+
+	signer := &tokens.Signer{Secret: []byte(cfg.Auth.TokenSecret)}
+
+	invitationHandler := &handlers.InvitationHandler{
+		Repo:    invitationRepo,
+		Mailer:  mail,
+		Tokens:  signer,
+		BaseURL: baseURL,
+	}
+
+	twoFactorRepo := &repository.TwoFactorRepository{
+		DB: dbPool,
+	}
+
+	loginAttemptRepo := &repository.LoginAttemptRepository{
+		DB: dbPool,
+	}
+
 	userHandler := &handlers.UserHandler{
-		Repo: userRepo,
+		Repo:                  userRepo,
+		Orgs:                  organizationRepo,
+		Invitations:           invitationRepo,
+		TwoFactor:             twoFactorRepo,
+		Attempts:              loginAttemptRepo,
+		JWT:                   tokenService,
+		Mailer:                mail,
+		Tokens:                signer,
+		BaseURL:               baseURL,
+		RequireVerifiedEmail:  cfg.Auth.RequireEmailVerification,
+		DefaultOrganization:   cfg.Auth.DefaultOrganization,
+		RegistrationMode:      cfg.Auth.RegistrationMode,
+		TOTPIssuer:            cfg.Auth.TOTPIssuer,
+		PasswordLoginDisabled: cfg.Auth.PasswordLoginDisabled,
+	}
+
+	// Single sign-on is enabled by setting OIDC_ISSUER_URL
+	var ssoHandler *handlers.SSOHandler
+	if cfg.OIDC.Enabled() {
+		provider, err := sso.New(context.Background(), sso.Config{
+			IssuerURL:    cfg.OIDC.IssuerURL,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			RoleClaim:    cfg.OIDC.RoleClaim,
+			AdminValues:  cfg.OIDC.AdminValues,
+		})
+		if err != nil {
+			slog.Error("Could not initialize OIDC provider", "error", err)
+			os.Exit(1)
+		}
+
+		ssoHandler = &handlers.SSOHandler{
+			Provider: provider,
+			Identities: &repository.IdentityRepository{
+				DB: dbPool,
+			},
+			Users:        userHandler,
+			Tokens:       signer,
+			Organization: cfg.OIDC.Organization,
+			SecureCookie: strings.HasPrefix(baseURL, "https://"),
+		}
+	}
+
+	reservationRepo := &repository.ReservationRepository{
+		DB:     dbPool,
+		System: systemPool,
+	}
+
+	reservationHandler := &handlers.ReservationHandler{
+		Repo: reservationRepo,
+	}
+
+	stockCountRepo := &repository.StockCountRepository{
+		DB:      dbPool,
+		Replica: replicaPool,
+	}
+
+	stockCountHandler := &handlers.StockCountHandler{
+		Repo: stockCountRepo,
 	}
 
+	returnRepo := &repository.ReturnRepository{
+		DB:      dbPool,
+		Replica: replicaPool,
+	}
+
+	returnHandler := &handlers.ReturnHandler{
+		Repo: returnRepo,
+	}
+
+	// Release expired reservations in the background (0 disables the sweeper)
+	sweepInterval := cfg.ReservationSweepInterval
+
+	// Cancelled on SIGINT/SIGTERM, which stops the sweeper and starts the shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if sweepInterval > 0 {
+		go runReservationSweeper(ctx, reservationRepo, sweepInterval)
+	}
+
+	apiKeyRepo := &repository.APIKeyRepository{
+		DB:     dbPool,
+		System: systemPool,
+	}
+
+	apiKeyHandler := &handlers.APIKeyHandler{
+		Repo: apiKeyRepo,
+	}
+
+	authenticator := &appMiddleware.Authenticator{
+		JWT:     tokenService,
+		APIKeys: apiKeyRepo,
+		Members: organizationRepo,
+	}
+
+	jwksHandler := &handlers.JWKSHandler{
+		Keys: keySet,
+	}
+
+	healthHandler := &handlers.HealthHandler{
+		Repo: &repository.HealthRepository{
+			DB:      dbPool,
+			Replica: replicaPool,
+		},
+		SchemaVersion: db.LatestVersion(),
+	}
+
+	adminHandler := &handlers.AdminHandler{
+		LoginAttempts:        loginAttemptRepo,
+		Members:              organizationRepo,
+		OperatorOrganization: cfg.Auth.OperatorOrganization,
+	}
+
+	appMetrics := metrics.New()
+	appMetrics.Register(metrics.NewPoolCollector(dbPool, "primary"))
+	if replicaPool != nil {
+		appMetrics.Register(metrics.NewPoolCollector(replicaPool, "replica"))
+	}
+	if systemPool != dbPool {
+		appMetrics.Register(metrics.NewPoolCollector(systemPool, "system"))
+	}
+	appMetrics.Register(metrics.NewInventoryCollector(productRepo, cfg.Metrics.LowStockThreshold))
+
 	r := chi.NewRouter()
 
-	r.Use(middleware.Logger)
+	// Only trust X-Forwarded-For / X-Real-IP when running behind our own proxy
+	if cfg.Server.TrustProxyHeaders {
+		r.Use(middleware.RealIP)
+	}
+	// Recoverer sits inside the request logger so a panic is logged as a 500
+	r.Use(middleware.RequestID)
+	r.Use(logging.Middleware(logger))
+	r.Use(tracing.Middleware)
+	r.Use(appMetrics.Middleware)
 	r.Use(middleware.Recoverer)
 
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		r.Use(cors.Handler(cors.Options{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Authorization", "Content-Type", "X-API-Key", middleware.RequestIDHeader, "traceparent", "tracestate"},
+			ExposedHeaders:   []string{middleware.RequestIDHeader},
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           int(cfg.CORS.MaxAge.Seconds()),
+		}))
+	}
+
+	// Probes and scrapes are not limited, an orchestrator polling them must never get 429
+	if cfg.RateLimit.Requests > 0 {
+		r.Use(skipProbes(httprate.LimitByIP(cfg.RateLimit.Requests, cfg.RateLimit.Window)))
+	}
+
+	r.Handle("/metrics", appMetrics.Handler(cfg.Metrics.Token))
+	r.Get("/healthz", healthHandler.Healthz)
+	r.Get("/readyz", healthHandler.Readyz)
+
+	// Every tenant resource needs a principal: its organization scopes the data
 	r.Route("/products", func(r chi.Router) {
-		r.Get("/", productHandler.GetAllProducts)
+		r.Use(authenticator.AuthMiddleware)
+		r.Use(appMiddleware.RequireScope("products"))
 
-		r.Group(func(r chi.Router) {
-			r.Use(appMiddleware.AuthMiddleware)
-			r.Post("/", productHandler.CreateProduct)
-		})
+		r.Get("/", productHandler.GetAllProducts)
+		r.Get("/search", productHandler.SearchProducts)
+		r.Post("/", productHandler.CreateProduct)
 
 		r.Route("/{id}", func(r chi.Router) {
 			r.Get("/", productHandler.GetProductByID)
+			r.Put("/", productHandler.UpdateProduct)
+			r.Delete("/", productHandler.DeleteProduct)
+			r.Get("/movements", productHandler.GetProductMovements)
+		})
+	})
+
+	r.Route("/categories", func(r chi.Router) {
+		r.Use(authenticator.AuthMiddleware)
+		r.Use(appMiddleware.RequireScope("categories"))
+
+		r.Get("/", categoryHandler.GetAllCategories)
+		r.Post("/", categoryHandler.CreateCategory)
+		r.Delete("/{id}", categoryHandler.DeleteCategory)
+	})
 
-			r.Group(func(r chi.Router) {
-				r.Use(appMiddleware.AuthMiddleware)
+	r.Route("/customers", func(r chi.Router) {
+		r.Use(authenticator.AuthMiddleware)
+		r.Use(appMiddleware.RequireScope("customers"))
+
+		r.Get("/", customerHandler.GetAllCustomers)
+		r.Post("/", customerHandler.CreateCustomer)
 
-				r.Put("/", productHandler.UpdateProduct)
-				r.Delete("/", productHandler.DeleteProduct)
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", customerHandler.GetCustomerByID)
+			r.Put("/", customerHandler.UpdateCustomer)
+			r.Post("/merge", customerHandler.MergeCustomers)
+
+			r.Route("/addresses", func(r chi.Router) {
+				r.Get("/", customerHandler.GetAddresses)
+				r.Post("/", customerHandler.CreateAddress)
+				r.Get("/{addressID}", customerHandler.GetAddress)
+				r.Put("/{addressID}", customerHandler.UpdateAddress)
+				r.Delete("/{addressID}", customerHandler.DeleteAddress)
 			})
 		})
 	})
 
-	r.Route("/categories", func(r chi.Router) {
-		r.Get("/", categoryHandler.GetAllCategories)
+	r.Route("/reservations", func(r chi.Router) {
+		r.Use(authenticator.AuthMiddleware)
+		r.Use(appMiddleware.RequireScope("reservations"))
 
-		r.Group(func(r chi.Router) {
-			r.Use(appMiddleware.AuthMiddleware)
-			r.Post("/", categoryHandler.CreateCategory)
-			r.Delete("/{id}", categoryHandler.DeleteCategory)
+		r.Get("/", reservationHandler.GetAllReservations)
+		r.Post("/", reservationHandler.CreateReservation)
+		r.Delete("/{id}", reservationHandler.ReleaseReservation)
+		r.Post("/{id}/fulfill", reservationHandler.FulfillReservation)
+	})
+
+	r.Route("/stock-counts", func(r chi.Router) {
+		r.Use(authenticator.AuthMiddleware)
+		r.Use(appMiddleware.RequireScope("stock-counts"))
+
+		r.Get("/", stockCountHandler.GetAllStockCounts)
+		r.Post("/", stockCountHandler.CreateStockCount)
+
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", stockCountHandler.GetStockCountByID)
+			r.Get("/variances", stockCountHandler.GetVariances)
+			r.Put("/lines", stockCountHandler.RecordCounts)
+			r.Post("/approve", stockCountHandler.ApproveStockCount)
+			r.Post("/cancel", stockCountHandler.CancelStockCount)
 		})
 	})
 
-	r.Route("/customers", func(r chi.Router) {
-		r.Get("/", customerHandler.GetAllCustomers)
+	r.Route("/returns", func(r chi.Router) {
+		r.Use(authenticator.AuthMiddleware)
+		r.Use(appMiddleware.RequireScope("returns"))
+
+		r.Get("/", returnHandler.GetAllReturns)
+		r.Post("/", returnHandler.CreateReturn)
+		r.Get("/{id}", returnHandler.GetReturnByID)
+		r.Post("/{id}/lines/{lineID}/resolve", returnHandler.ResolveQuarantine)
+	})
 
-		r.Group(func(r chi.Router) {
-			r.Use(appMiddleware.AuthMiddleware)
-			r.Post("/", customerHandler.CreateCustomer)
+	r.Route("/api-keys", func(r chi.Router) {
+		r.Use(authenticator.AuthMiddleware)
+		r.Use(appMiddleware.RequireUser)
+
+		r.Get("/", apiKeyHandler.GetAPIKeys)
+		r.Post("/", apiKeyHandler.CreateAPIKey)
+		r.Delete("/{id}", apiKeyHandler.RevokeAPIKey)
+	})
+
+	r.Route("/organizations", func(r chi.Router) {
+		r.Use(authenticator.AuthMiddleware)
+		r.Use(appMiddleware.RequireUser)
+
+		r.Get("/", organizationHandler.GetMyOrganizations)
+		r.With(appMiddleware.RequireRole(repository.RoleAdmin)).Post("/", organizationHandler.CreateOrganization)
+	})
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(authenticator.AuthMiddleware)
+		r.Use(appMiddleware.RequireRole(repository.RoleAdmin))
+
+		r.Get("/lockouts", adminHandler.GetLockouts)
+		r.Delete("/lockouts/{scope}/{key}", adminHandler.Unlock)
+
+		r.Get("/users", adminHandler.GetUsers)
+		r.Route("/users/{id}", func(r chi.Router) {
+			r.Get("/", adminHandler.GetUser)
+			r.Delete("/", adminHandler.DeleteUser)
+			r.Put("/role", adminHandler.UpdateUserRole)
+			r.Post("/deactivate", adminHandler.DeactivateUser)
+			r.Post("/activate", adminHandler.ActivateUser)
+		})
+
+		r.Get("/invitations", invitationHandler.GetInvitations)
+		r.Post("/invitations", invitationHandler.CreateInvitation)
+		r.Delete("/invitations/{id}", invitationHandler.RevokeInvitation)
+	})
+
+	r.Route("/me", func(r chi.Router) {
+		r.Use(authenticator.AuthMiddleware)
+		r.Use(appMiddleware.RequireUser)
+
+		r.Get("/", userHandler.GetMe)
+		r.Put("/password", userHandler.ChangePassword)
+		r.Post("/invitations/accept", userHandler.AcceptInvitation)
+
+		r.Route("/2fa", func(r chi.Router) {
+			r.Post("/setup", userHandler.SetupTwoFactor)
+			r.Post("/enable", userHandler.EnableTwoFactor)
+			r.Post("/disable", userHandler.DisableTwoFactor)
+			r.Post("/recovery-codes", userHandler.RegenerateRecoveryCodes)
 		})
 	})
 
+	r.Get("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
 	r.Post("/register", userHandler.RegisterUser)
 	r.Post("/login", userHandler.LoginUser)
+	r.Post("/login/2fa", userHandler.LoginTwoFactor)
+	r.Post("/verify-email", userHandler.VerifyEmail)
+	r.Post("/verify-email/resend", userHandler.ResendVerification)
+	r.Post("/password/forgot", userHandler.ForgotPassword)
+	r.Post("/password/reset", userHandler.ResetPassword)
+
+	if ssoHandler != nil {
+		r.Get("/auth/oidc/login", ssoHandler.Login)
+		r.Get("/auth/oidc/callback", ssoHandler.Callback)
+	}
 
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Welcome to the Inventory API"))
 	})
 
-	slog.Info("Starting starting...", "port", port)
+	// otelhttp starts the server span (continuing an incoming traceparent) around the router
+	handler := otelhttp.NewHandler(r, "http.server", otelhttp.WithFilter(func(r *http.Request) bool {
+		return !isProbe(r.URL.Path)
+	}))
+
+	server := &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	slog.Info("Starting starting...", "port", cfg.Server.Port)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
 
-	if err := http.ListenAndServe(":"+port, r); err != nil {
+	select {
+	case err := <-serverErr:
 		slog.Error("Server failed to start", "error", err)
+	case <-ctx.Done():
+		slog.Info("Shutting down")
+	}
+	stop()
+
+	// Drain in-flight requests, then flush batched spans; the deferred pool
+	// closes run last
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed drain requests", "error", err)
+	}
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Failed flush traces", "error", err)
+	}
+}
+
+// runReservationSweeper periodically releases reservations whose TTL has passed.
+func runReservationSweeper(ctx context.Context, repo *repository.ReservationRepository, interval time.Duration) {
+	ctx = logging.With(ctx, "job", "reservation_sweeper")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := repo.ReleaseExpiredReservations(ctx)
+			if err != nil {
+				logging.FromContext(ctx).Error("Reservation sweeper failed", "error", err)
+				continue
+			}
+			if released > 0 {
+				logging.FromContext(ctx).Info("Released expired reservations", "count", released)
+			}
+		}
+	}
+}
+
+// isProbe reports whether path is a health probe or the metrics scrape.
+func isProbe(path string) bool {
+	switch path {
+	case "/metrics", "/healthz", "/readyz":
+		return true
+	}
+	return false
+}
+
+// skipProbes applies mw to every request except probes and scrapes.
+func skipProbes(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isProbe(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
 	}
 }
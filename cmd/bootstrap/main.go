@@ -0,0 +1,78 @@
+// Command bootstrap creates the first admin of an organization, for fresh
+// deployments where registration is invite-only or disabled.
+//
+//	BOOTSTRAP_PASSWORD=... go run ./cmd/bootstrap -email admin@example.com
+//
+// The organization (-org, default "default") is created when it does not exist.
+// The command refuses to run once the organization already has an admin.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
+
+	"inventory-api/internal/config"
+	"inventory-api/internal/database"
+	"inventory-api/internal/repository"
+)
+
+func main() {
+	email := flag.String("email", "", "admin email (required)")
+	orgSlug := flag.String("org", "default", "organization slug")
+	orgName := flag.String("org-name", "Default", "organization name, used when the organization is created")
+	flag.Parse()
+
+	_ = godotenv.Load()
+
+	// The password is read from the environment so it does not end up in shell history
+	password := os.Getenv("BOOTSTRAP_PASSWORD")
+
+	if *email == "" || password == "" {
+		fmt.Fprintln(os.Stderr, "-email and BOOTSTRAP_PASSWORD are required")
+		os.Exit(2)
+	}
+
+	if err := run(os.Getenv("DATABASE_URL"), *email, password, *orgSlug, *orgName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(connString, email, password, orgSlug, orgName string) error {
+	user := repository.User{Email: email, Password: password}
+	org := repository.Organization{Name: orgName, Slug: orgSlug}
+
+	validate := validator.New()
+	if err := validate.Struct(user); err != nil {
+		return fmt.Errorf("invalid admin: %w", err)
+	}
+	if err := validate.Struct(org); err != nil {
+		return fmt.Errorf("invalid organization: %w", err)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed hash password: %w", err)
+	}
+	user.Password = string(hashedPassword)
+
+	dbPool, err := database.InitDB(config.Database{URL: connString})
+	if err != nil {
+		return err
+	}
+	defer dbPool.Close()
+
+	orgs := &repository.OrganizationRepository{DB: dbPool}
+	if err := orgs.BootstrapAdmin(context.Background(), &org, &user); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created admin %s (%s) in organization %s (%s)\n", user.Email, user.ID, org.Slug, org.ID)
+	return nil
+}
@@ -0,0 +1,88 @@
+// Command jwtkeygen writes a new JWT signing key for the API.
+//
+//	go run ./cmd/jwtkeygen -dir keys -kid 2026-10 -alg EdDSA
+//
+// It writes <dir>/<kid>.pem (private) and <dir>/<kid>.pub.pem (public). Keep
+// only the .pub.pem of retired keys so old tokens still verify until they expire.
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	dir := flag.String("dir", "keys", "directory to write the key files to")
+	kid := flag.String("kid", "", "key ID (required), e.g. 2026-10")
+	alg := flag.String("alg", "EdDSA", "EdDSA or RS256")
+	flag.Parse()
+
+	if *kid == "" {
+		fmt.Fprintln(os.Stderr, "-kid is required")
+		os.Exit(2)
+	}
+
+	if err := run(*dir, *kid, *alg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, kid, alg string) error {
+	var priv crypto.Signer
+	var err error
+
+	switch alg {
+	case "EdDSA":
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	case "RS256":
+		priv, err = rsa.GenerateKey(rand.Reader, 3072)
+	default:
+		return fmt.Errorf("unsupported alg %q", alg)
+	}
+	if err != nil {
+		return fmt.Errorf("failed generate key: %w", err)
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return fmt.Errorf("failed encode private key: %w", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		return fmt.Errorf("failed encode public key: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed create key dir: %w", err)
+	}
+
+	privPath := filepath.Join(dir, kid+".pem")
+	if err := writePEM(privPath, "PRIVATE KEY", privDER, 0o600); err != nil {
+		return err
+	}
+	if err := writePEM(filepath.Join(dir, kid+".pub.pem"), "PUBLIC KEY", pubDER, 0o644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s (set JWT_ACTIVE_KID=%s to sign with it)\n", privPath, kid)
+	return nil
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return fmt.Errorf("failed create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	return pem.Encode(f, &pem.Block{Type: blockType, Bytes: der})
+}
@@ -0,0 +1,32 @@
+// Package db embeds the SQL migrations (applied with golang-migrate) so the
+// API knows which schema version it was built for.
+package db
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// LatestVersion returns the highest migration version, i.e. the schema
+// version this build of the API expects.
+func LatestVersion() uint {
+	entries, _ := fs.ReadDir(migrations, "migrations")
+
+	var latest uint
+	for _, e := range entries {
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		if err == nil && uint(v) > latest {
+			latest = uint(v)
+		}
+	}
+	return latest
+}
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS stock_counts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'approved', 'cancelled')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    closed_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS stock_count_lines (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stock_count_id UUID NOT NULL REFERENCES stock_counts(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(50) NOT NULL,
+    expected_quantity INT NOT NULL,
+    counted_quantity INT CHECK (counted_quantity >= 0),
+    counted_at TIMESTAMP,
+    UNIQUE (stock_count_id, product_id)
+);
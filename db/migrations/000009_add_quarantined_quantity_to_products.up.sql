@@ -0,0 +1,2 @@
+ALTER TABLE products
+ADD COLUMN quarantined_quantity INT NOT NULL DEFAULT 0;
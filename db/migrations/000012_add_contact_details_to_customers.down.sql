@@ -0,0 +1,3 @@
+ALTER TABLE customers
+DROP COLUMN notes,
+DROP COLUMN tax_id;
@@ -0,0 +1,3 @@
+ALTER TABLE customers
+ADD COLUMN tax_id VARCHAR(50) NOT NULL DEFAULT '',
+ADD COLUMN notes TEXT NOT NULL DEFAULT '';
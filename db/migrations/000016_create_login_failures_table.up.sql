@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS login_failures (
+    scope VARCHAR(10) NOT NULL CHECK (scope IN ('email', 'ip')),
+    key VARCHAR(255) NOT NULL,
+    failed_count INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (scope, key)
+);
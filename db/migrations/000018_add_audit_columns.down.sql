@@ -0,0 +1,11 @@
+ALTER TABLE customers
+DROP COLUMN updated_by,
+DROP COLUMN created_by;
+
+ALTER TABLE categories
+DROP COLUMN updated_by,
+DROP COLUMN created_by;
+
+ALTER TABLE products
+DROP COLUMN updated_by,
+DROP COLUMN created_by;
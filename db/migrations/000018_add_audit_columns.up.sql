@@ -0,0 +1,11 @@
+ALTER TABLE products
+ADD COLUMN created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+ADD COLUMN updated_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE categories
+ADD COLUMN created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+ADD COLUMN updated_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE customers
+ADD COLUMN created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+ADD COLUMN updated_by UUID REFERENCES users(id) ON DELETE SET NULL;
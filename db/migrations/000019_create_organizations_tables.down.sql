@@ -0,0 +1,9 @@
+ALTER TABLE users
+ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'staff'
+    CHECK (role IN ('admin', 'staff'));
+
+UPDATE users u SET role = 'admin'
+WHERE EXISTS (SELECT 1 FROM organization_members m WHERE m.user_id = u.id AND m.role = 'admin');
+
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
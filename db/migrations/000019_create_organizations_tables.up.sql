@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'staff' CHECK (role IN ('admin', 'staff')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user ON organization_members (user_id);
+
+-- Everything that exists today belongs to one default organization
+INSERT INTO organizations (name, slug) VALUES ('Default', 'default');
+
+INSERT INTO organization_members (organization_id, user_id, role)
+SELECT o.id, u.id, u.role FROM users u CROSS JOIN organizations o WHERE o.slug = 'default';
+
+-- Roles are per organization from now on
+ALTER TABLE users DROP COLUMN role;
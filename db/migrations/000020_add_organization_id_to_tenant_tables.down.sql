@@ -0,0 +1,66 @@
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+ALTER TABLE api_keys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE api_keys DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON return_lines;
+ALTER TABLE return_lines NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE return_lines DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON returns;
+ALTER TABLE returns NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE returns DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON stock_count_lines;
+ALTER TABLE stock_count_lines NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE stock_count_lines DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON stock_counts;
+ALTER TABLE stock_counts NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE stock_counts DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON stock_movements;
+ALTER TABLE stock_movements NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE stock_movements DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON stock_reservations;
+ALTER TABLE stock_reservations NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE stock_reservations DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON customer_addresses;
+ALTER TABLE customer_addresses NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE customer_addresses DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON customers;
+ALTER TABLE customers NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE customers DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON categories;
+ALTER TABLE categories NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE categories DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON products;
+ALTER TABLE products NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE products DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_organization_email_key;
+ALTER TABLE customers ADD CONSTRAINT customers_email_key UNIQUE (email);
+
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_organization_name_key;
+ALTER TABLE categories ADD CONSTRAINT categories_name_key UNIQUE (name);
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_organization_sku_key;
+ALTER TABLE products ADD CONSTRAINT products_sku_key UNIQUE (sku);
+
+ALTER TABLE api_keys DROP COLUMN organization_id;
+ALTER TABLE return_lines DROP COLUMN organization_id;
+ALTER TABLE returns DROP COLUMN organization_id;
+ALTER TABLE stock_count_lines DROP COLUMN organization_id;
+ALTER TABLE stock_counts DROP COLUMN organization_id;
+ALTER TABLE stock_movements DROP COLUMN organization_id;
+ALTER TABLE stock_reservations DROP COLUMN organization_id;
+ALTER TABLE customer_addresses DROP COLUMN organization_id;
+ALTER TABLE customers DROP COLUMN organization_id;
+ALTER TABLE categories DROP COLUMN organization_id;
+ALTER TABLE products DROP COLUMN organization_id;
+
+DROP FUNCTION IF EXISTS app_current_org();
\ No newline at end of file
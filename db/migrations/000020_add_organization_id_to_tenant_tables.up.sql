@@ -0,0 +1,161 @@
+-- Current tenant, set per connection by the API from the authenticated principal.
+-- NULL (unset) means a system context such as migrations or background jobs.
+CREATE OR REPLACE FUNCTION app_current_org() RETURNS UUID
+LANGUAGE sql STABLE AS $$
+    SELECT NULLIF(current_setting('app.current_org', true), '')::uuid
+$$;
+
+ALTER TABLE products
+ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE
+    DEFAULT app_current_org();
+UPDATE products SET organization_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE products ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_products_organization ON products (organization_id);
+
+ALTER TABLE categories
+ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE
+    DEFAULT app_current_org();
+UPDATE categories SET organization_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE categories ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_categories_organization ON categories (organization_id);
+
+ALTER TABLE customers
+ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE
+    DEFAULT app_current_org();
+UPDATE customers SET organization_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE customers ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_customers_organization ON customers (organization_id);
+
+ALTER TABLE customer_addresses
+ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE
+    DEFAULT app_current_org();
+UPDATE customer_addresses SET organization_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE customer_addresses ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_customer_addresses_organization ON customer_addresses (organization_id);
+
+ALTER TABLE stock_reservations
+ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE
+    DEFAULT app_current_org();
+UPDATE stock_reservations SET organization_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE stock_reservations ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_organization ON stock_reservations (organization_id);
+
+ALTER TABLE stock_movements
+ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE
+    DEFAULT app_current_org();
+UPDATE stock_movements SET organization_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE stock_movements ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_stock_movements_organization ON stock_movements (organization_id);
+
+ALTER TABLE stock_counts
+ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE
+    DEFAULT app_current_org();
+UPDATE stock_counts SET organization_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE stock_counts ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_stock_counts_organization ON stock_counts (organization_id);
+
+ALTER TABLE stock_count_lines
+ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE
+    DEFAULT app_current_org();
+UPDATE stock_count_lines SET organization_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE stock_count_lines ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_stock_count_lines_organization ON stock_count_lines (organization_id);
+
+ALTER TABLE returns
+ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE
+    DEFAULT app_current_org();
+UPDATE returns SET organization_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE returns ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_returns_organization ON returns (organization_id);
+
+ALTER TABLE return_lines
+ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE
+    DEFAULT app_current_org();
+UPDATE return_lines SET organization_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE return_lines ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_return_lines_organization ON return_lines (organization_id);
+
+ALTER TABLE api_keys
+ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE
+    DEFAULT app_current_org();
+UPDATE api_keys SET organization_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE api_keys ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_api_keys_organization ON api_keys (organization_id);
+
+-- SKUs, category names and customer emails are unique per tenant instead of globally
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_sku_key;
+ALTER TABLE products ADD CONSTRAINT products_organization_sku_key UNIQUE (organization_id, sku);
+
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_name_key;
+ALTER TABLE categories ADD CONSTRAINT categories_organization_name_key UNIQUE (organization_id, name);
+
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_email_key;
+ALTER TABLE customers ADD CONSTRAINT customers_organization_email_key UNIQUE (organization_id, email);
+
+-- Row-level security is a safety net behind the tenant filters in every query.
+-- It does not apply to superusers, so run the API as a regular role.
+ALTER TABLE products ENABLE ROW LEVEL SECURITY;
+ALTER TABLE products FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON products
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER TABLE categories ENABLE ROW LEVEL SECURITY;
+ALTER TABLE categories FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON categories
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER TABLE customers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE customers FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON customers
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER TABLE customer_addresses ENABLE ROW LEVEL SECURITY;
+ALTER TABLE customer_addresses FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON customer_addresses
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER TABLE stock_reservations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE stock_reservations FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON stock_reservations
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER TABLE stock_movements ENABLE ROW LEVEL SECURITY;
+ALTER TABLE stock_movements FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON stock_movements
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER TABLE stock_counts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE stock_counts FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON stock_counts
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER TABLE stock_count_lines ENABLE ROW LEVEL SECURITY;
+ALTER TABLE stock_count_lines FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON stock_count_lines
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER TABLE returns ENABLE ROW LEVEL SECURITY;
+ALTER TABLE returns FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON returns
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER TABLE return_lines ENABLE ROW LEVEL SECURITY;
+ALTER TABLE return_lines FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON return_lines
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON api_keys
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
@@ -0,0 +1,3 @@
+-- Deactivated members keep their history but can no longer log in or use API keys
+ALTER TABLE organization_members
+ADD COLUMN deactivated_at TIMESTAMP;
@@ -0,0 +1,16 @@
+-- totp_secret is set on setup; 2FA only applies once totp_enabled_at is set.
+-- totp_last_step stops a code from being replayed within its validity window.
+ALTER TABLE users
+ADD COLUMN totp_secret VARCHAR(64),
+ADD COLUMN totp_enabled_at TIMESTAMPTZ,
+ADD COLUMN totp_last_step BIGINT;
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user ON user_recovery_codes (user_id);
@@ -0,0 +1,49 @@
+COMMENT ON FUNCTION app_current_org() IS NULL;
+
+ALTER POLICY tenant_isolation ON invitations
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON api_keys
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON return_lines
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON returns
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON stock_count_lines
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON stock_counts
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON stock_movements
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON stock_reservations
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON customer_addresses
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON customers
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON categories
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON products
+    USING (app_current_org() IS NULL OR organization_id = app_current_org())
+    WITH CHECK (app_current_org() IS NULL OR organization_id = app_current_org());
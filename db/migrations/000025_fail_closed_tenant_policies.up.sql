@@ -0,0 +1,57 @@
+-- Tenant policies fail closed: a connection without app.current_org sees and
+-- writes no tenant rows. Cross-tenant system work (API key lookup, invitation
+-- acceptance, the reservation sweeper, metrics) connects as a separate role
+-- with BYPASSRLS instead, e.g.
+--   CREATE ROLE inventory_system LOGIN BYPASSRLS PASSWORD '...';
+--   GRANT <api role> TO inventory_system;
+-- configured as DATABASE_SYSTEM_URL.
+COMMENT ON FUNCTION app_current_org() IS
+    'Organization of the authenticated principal; NULL matches no tenant rows.';
+
+ALTER POLICY tenant_isolation ON products
+    USING (organization_id = app_current_org())
+    WITH CHECK (organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON categories
+    USING (organization_id = app_current_org())
+    WITH CHECK (organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON customers
+    USING (organization_id = app_current_org())
+    WITH CHECK (organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON customer_addresses
+    USING (organization_id = app_current_org())
+    WITH CHECK (organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON stock_reservations
+    USING (organization_id = app_current_org())
+    WITH CHECK (organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON stock_movements
+    USING (organization_id = app_current_org())
+    WITH CHECK (organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON stock_counts
+    USING (organization_id = app_current_org())
+    WITH CHECK (organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON stock_count_lines
+    USING (organization_id = app_current_org())
+    WITH CHECK (organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON returns
+    USING (organization_id = app_current_org())
+    WITH CHECK (organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON return_lines
+    USING (organization_id = app_current_org())
+    WITH CHECK (organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON api_keys
+    USING (organization_id = app_current_org())
+    WITH CHECK (organization_id = app_current_org());
+
+ALTER POLICY tenant_isolation ON invitations
+    USING (organization_id = app_current_org())
+    WITH CHECK (organization_id = app_current_org());
@@ -0,0 +1,2 @@
+-- Expired login states are pruned whenever a new one is created
+CREATE INDEX IF NOT EXISTS idx_oidc_login_states_expires_at ON oidc_login_states (expires_at);
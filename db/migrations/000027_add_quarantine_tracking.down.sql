@@ -0,0 +1,4 @@
+ALTER TABLE return_lines DROP CONSTRAINT IF EXISTS return_lines_resolved_quantity_check;
+ALTER TABLE return_lines DROP COLUMN IF EXISTS resolved_quantity;
+
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS quarantined_change;
@@ -0,0 +1,9 @@
+-- Movements also record changes to quarantined_quantity, so returned stock can
+-- be followed into quarantine and out again (released for sale or scrapped).
+-- Quarantine lines created before this migration have no inbound movement.
+ALTER TABLE stock_movements ADD COLUMN quarantined_change INT NOT NULL DEFAULT 0;
+
+-- How much of a quarantine line has been released or scrapped so far
+ALTER TABLE return_lines ADD COLUMN resolved_quantity INT NOT NULL DEFAULT 0;
+ALTER TABLE return_lines ADD CONSTRAINT return_lines_resolved_quantity_check
+    CHECK (resolved_quantity >= 0 AND resolved_quantity <= quantity);
@@ -0,0 +1,3 @@
+-- On-hand stock can never go negative. Fix any negative rows (e.g. with a
+-- stock count) before running this migration, otherwise it fails.
+ALTER TABLE products ADD CONSTRAINT products_quantity_check CHECK (quantity >= 0);
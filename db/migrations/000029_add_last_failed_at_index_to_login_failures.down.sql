@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_login_failures_last_failed_at;
@@ -0,0 +1,2 @@
+-- Stale counters are pruned by last_failed_at on every login attempt
+CREATE INDEX IF NOT EXISTS idx_login_failures_last_failed_at ON login_failures (scope, last_failed_at);
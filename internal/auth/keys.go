@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySet holds the key used to sign new tokens plus every key still accepted
+// for verification, each identified by its kid.
+//
+// Keys are loaded from a directory: "<kid>.pem" holds a private key (PKCS#8,
+// or PKCS#1 for RSA) and "<kid>.pub.pem" a public-only key (PKIX). To rotate,
+// add a new private key, point the active kid at it, and keep the old key (or
+// just its public half) until tokens signed with it have expired.
+type KeySet struct {
+	activeKID string
+	signer    crypto.Signer
+	keys      map[string]verificationKey
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// LoadKeySet reads every key in dir. activeKID selects the signing key; it may
+// be empty when the directory holds exactly one private key.
+func LoadKeySet(dir, activeKID string) (*KeySet, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("no JWT key directory configured")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed read key directory: %w", err)
+	}
+
+	ks := &KeySet{keys: map[string]verificationKey{}}
+	signers := map[string]crypto.Signer{}
+	publics := map[string]crypto.PublicKey{}
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".pem") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed read key %s: %w", name, err)
+		}
+
+		if kid, ok := strings.CutSuffix(name, ".pub.pem"); ok {
+			if publics[kid], err = parsePublicKey(data); err != nil {
+				return nil, fmt.Errorf("key %s: %w", name, err)
+			}
+			continue
+		}
+
+		kid := strings.TrimSuffix(name, ".pem")
+		if signers[kid], err = parsePrivateKey(data); err != nil {
+			return nil, fmt.Errorf("key %s: %w", name, err)
+		}
+	}
+
+	// A .pub.pem next to its private key is redundant; the private key wins
+	for kid, priv := range signers {
+		publics[kid] = priv.Public()
+	}
+	for kid, pub := range publics {
+		if err := ks.add(kid, pub); err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		}
+	}
+
+	if activeKID == "" && len(signers) == 1 {
+		for kid := range signers {
+			activeKID = kid
+		}
+	}
+
+	signer, ok := signers[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("no private key for active kid %q in %s", activeKID, dir)
+	}
+
+	ks.activeKID = activeKID
+	ks.signer = signer
+	return ks, nil
+}
+
+func (ks *KeySet) add(kid string, pub crypto.PublicKey) error {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		ks.keys[kid] = verificationKey{method: jwt.SigningMethodRS256, public: pub}
+	case ed25519.PublicKey:
+		ks.keys[kid] = verificationKey{method: jwt.SigningMethodEdDSA, public: pub}
+	default:
+		return fmt.Errorf("unsupported key type %T, use RSA or Ed25519", pub)
+	}
+	return nil
+}
+
+// Sign signs claims with the active key and sets the kid header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.keys[ks.activeKID].method, claims)
+	token.Header["kid"] = ks.activeKID
+	return token.SignedString(ks.signer)
+}
+
+// Keyfunc resolves the verification key from the token's kid header and makes
+// sure the token uses the algorithm that key was issued for.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for kid %q", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// ValidMethods lists the algorithms accepted by Keyfunc, for jwt.WithValidMethods.
+func (ks *KeySet) ValidMethods() []string {
+	return []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every verification key in RFC 7517 form.
+func (ks *KeySet) JWKS() JWKSet {
+	kids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, kid := range kids {
+		key := ks.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed parse private key: %w", err)
+	}
+	return key, nil
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed parse public key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Authentication methods recorded on a Principal
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID string
+	Email  string
+	// OrganizationID is the tenant every repository query is scoped to.
+	OrganizationID string
+	// Roles are the user's roles within OrganizationID.
+	Roles      []string
+	AuthMethod string
+	// Scopes limits what an API key may do; empty for user logins.
+	Scopes []string
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// principalKey is unexported so no other package can read or overwrite the
+// principal except through the functions below.
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal stored by the auth middleware, if any.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// UserID returns the authenticated user's ID, or "" for anonymous requests.
+func UserID(ctx context.Context) string {
+	if p, ok := PrincipalFrom(ctx); ok {
+		return p.UserID
+	}
+	return ""
+}
+
+// OrganizationID returns the authenticated caller's organization, or "" for
+// anonymous requests and background jobs.
+func OrganizationID(ctx context.Context) string {
+	if p, ok := PrincipalFrom(ctx); ok {
+		return p.OrganizationID
+	}
+	return ""
+}
@@ -0,0 +1,426 @@
+// Package config loads the API configuration into a typed struct.
+//
+// Values are layered: built-in defaults, then the optional YAML file, then
+// environment variables (a .env file is loaded into the environment first and
+// never overrides variables that are already set). Every setting has an
+// environment variable, named in its env tag.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/joho/godotenv"
+	"go.yaml.in/yaml/v3"
+)
+
+type Config struct {
+	Server    Server    `yaml:"server"`
+	Database  Database  `yaml:"database"`
+	JWT       JWT       `yaml:"jwt"`
+	Auth      Auth      `yaml:"auth"`
+	OIDC      OIDC      `yaml:"oidc"`
+	Mail      Mail      `yaml:"mail"`
+	CORS      CORS      `yaml:"cors"`
+	RateLimit RateLimit `yaml:"rate_limit"`
+	Metrics   Metrics   `yaml:"metrics"`
+	Tracing   Tracing   `yaml:"tracing"`
+
+	// PhoneDefaultCountryCode is used for customer phone numbers without a country code.
+	PhoneDefaultCountryCode string `yaml:"phone_default_country_code" env:"PHONE_DEFAULT_COUNTRY_CODE"`
+	// ReservationSweepInterval is how often expired reservations are released (0 disables).
+	ReservationSweepInterval time.Duration `yaml:"reservation_sweep_interval" env:"RESERVATION_SWEEP_INTERVAL" validate:"gte=0"`
+}
+
+type Server struct {
+	Port string `yaml:"port" env:"PORT" validate:"required,numeric"`
+	// BaseURL is the public URL used in emailed links; defaults to http://localhost:<port>.
+	BaseURL string `yaml:"base_url" env:"APP_BASE_URL" validate:"omitempty,url"`
+	// TrustProxyHeaders takes the client IP from X-Forwarded-For / X-Real-IP.
+	TrustProxyHeaders bool          `yaml:"trust_proxy_headers" env:"TRUST_PROXY_HEADERS"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT" validate:"gte=0"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" validate:"gte=0"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" validate:"gte=0"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" validate:"gte=0"`
+	// ShutdownTimeout is how long in-flight requests get to finish on SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" validate:"gte=0"`
+}
+
+type Database struct {
+	URL string `yaml:"url" env:"DATABASE_URL" secret:"url" validate:"required"`
+	// ReplicaURL optionally points at a read replica for listings and reports.
+	// It gets the same pool settings as the primary.
+	ReplicaURL string `yaml:"replica_url" env:"DATABASE_REPLICA_URL" secret:"url"`
+	// SystemURL connects as a role with BYPASSRLS for cross-tenant work (API
+	// key lookup, invitation acceptance, the reservation sweeper, metrics).
+	// Empty uses DATABASE_URL, which only works if that role bypasses RLS.
+	SystemURL string `yaml:"system_url" env:"DATABASE_SYSTEM_URL" secret:"url"`
+	// MaxConns and MinConns size the pool; 0 keeps the pgxpool default.
+	MaxConns int32 `yaml:"max_conns" env:"DB_MAX_CONNS" validate:"gte=0"`
+	MinConns int32 `yaml:"min_conns" env:"DB_MIN_CONNS" validate:"gte=0"`
+	// MaxConnLifetime, MaxConnIdleTime and HealthCheckPeriod keep the pgxpool default when 0.
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME" validate:"gte=0"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME" validate:"gte=0"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period" env:"DB_HEALTH_CHECK_PERIOD" validate:"gte=0"`
+	// StatementTimeout makes Postgres cancel any statement running longer (0 = no limit).
+	StatementTimeout time.Duration `yaml:"statement_timeout" env:"DB_STATEMENT_TIMEOUT" validate:"gte=0"`
+	// ConnectTimeout bounds each connection attempt at startup.
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT" validate:"gt=0"`
+	// ConnectMaxWait is how long startup keeps retrying while Postgres is not
+	// up yet (0 = try once).
+	ConnectMaxWait time.Duration `yaml:"connect_max_wait" env:"DB_CONNECT_MAX_WAIT" validate:"gte=0"`
+}
+
+type JWT struct {
+	KeysDir   string        `yaml:"keys_dir" env:"JWT_KEYS_DIR" validate:"required"`
+	ActiveKID string        `yaml:"active_kid" env:"JWT_ACTIVE_KID"`
+	Issuer    string        `yaml:"issuer" env:"JWT_ISSUER" validate:"required"`
+	Audience  string        `yaml:"audience" env:"JWT_AUDIENCE" validate:"required"`
+	TTL       time.Duration `yaml:"ttl" env:"JWT_TTL" validate:"gt=0"`
+	Leeway    time.Duration `yaml:"leeway" env:"JWT_LEEWAY" validate:"gte=0"`
+}
+
+type Auth struct {
+	// TokenSecret signs emailed links, invitations and login challenges.
+	TokenSecret              string `yaml:"token_secret" env:"TOKEN_SECRET" secret:"true" validate:"required"`
+	RegistrationMode         string `yaml:"registration_mode" env:"REGISTRATION_MODE" validate:"oneof=open invite disabled"`
+	DefaultOrganization      string `yaml:"default_organization" env:"DEFAULT_ORGANIZATION" validate:"required"`
+	RequireEmailVerification bool   `yaml:"require_email_verification" env:"REQUIRE_EMAIL_VERIFICATION"`
+	TOTPIssuer               string `yaml:"totp_issuer" env:"TOTP_ISSUER" validate:"required"`
+	// PasswordLoginDisabled leaves single sign-on as the only login; it needs OIDC.
+	PasswordLoginDisabled bool `yaml:"password_login_disabled" env:"PASSWORD_LOGIN_DISABLED"`
+	// OperatorOrganization is the organization whose admins operate the whole
+	// deployment, e.g. see and clear per-IP login lockouts. Defaults to the
+	// default organization.
+	OperatorOrganization string `yaml:"operator_organization" env:"OPERATOR_ORGANIZATION"`
+}
+
+// OIDC enables single sign-on when IssuerURL is set.
+type OIDC struct {
+	IssuerURL    string `yaml:"issuer_url" env:"OIDC_ISSUER_URL" validate:"omitempty,url"`
+	ClientID     string `yaml:"client_id" env:"OIDC_CLIENT_ID" validate:"required_with=IssuerURL"`
+	ClientSecret string `yaml:"client_secret" env:"OIDC_CLIENT_SECRET" secret:"true"`
+	// RedirectURL defaults to <base_url>/auth/oidc/callback.
+	RedirectURL string   `yaml:"redirect_url" env:"OIDC_REDIRECT_URL" validate:"omitempty,url"`
+	RoleClaim   string   `yaml:"role_claim" env:"OIDC_ROLE_CLAIM"`
+	AdminValues []string `yaml:"admin_values" env:"OIDC_ADMIN_VALUES"`
+	// Organization defaults to the default organization.
+	Organization string `yaml:"organization" env:"OIDC_ORGANIZATION"`
+}
+
+func (o OIDC) Enabled() bool {
+	return o.IssuerURL != ""
+}
+
+// Mail selects how transactional email is delivered. Only smtp reaches real
+// inboxes; log and file are for development.
+type Mail struct {
+	Driver string `yaml:"driver" env:"MAIL_DRIVER" validate:"omitempty,oneof=log file smtp"`
+	From   string `yaml:"from" env:"MAIL_FROM"`
+	Dir    string `yaml:"dir" env:"MAIL_DIR"`
+	// LogBodies makes the log driver print links, which carry live reset,
+	// verification and invitation tokens. Never enable it outside development.
+	LogBodies bool `yaml:"log_bodies" env:"MAIL_LOG_BODIES"`
+
+	// SMTP server for the smtp driver; STARTTLS is used when offered.
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtp_port" env:"SMTP_PORT" validate:"gte=1,lte=65535"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+}
+
+// CORS is disabled while AllowedOrigins is empty.
+type CORS struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowCredentials bool          `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"max_age" env:"CORS_MAX_AGE" validate:"gte=0"`
+}
+
+// RateLimit caps requests per client IP; Requests 0 disables it.
+type RateLimit struct {
+	Requests int           `yaml:"requests" env:"RATE_LIMIT_REQUESTS" validate:"gte=0"`
+	Window   time.Duration `yaml:"window" env:"RATE_LIMIT_WINDOW" validate:"gt=0"`
+}
+
+type Metrics struct {
+	// Token, when set, must be sent by scrapers as a bearer token.
+	Token             string `yaml:"token" env:"METRICS_TOKEN" secret:"true"`
+	LowStockThreshold int    `yaml:"low_stock_threshold" env:"LOW_STOCK_THRESHOLD" validate:"gte=0"`
+}
+
+type Tracing struct {
+	// Exporter is "none" or "otlp"; the collector is set with OTEL_EXPORTER_OTLP_ENDPOINT.
+	Exporter    string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" validate:"oneof=none otlp"`
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME" validate:"required"`
+}
+
+// Default returns the configuration used for anything not set elsewhere.
+func Default() Config {
+	return Config{
+		Server: Server{
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+		},
+		Database: Database{
+			StatementTimeout: 30 * time.Second,
+			ConnectTimeout:   5 * time.Second,
+			ConnectMaxWait:   time.Minute,
+		},
+		JWT: JWT{
+			Issuer:   "inventory-api",
+			Audience: "inventory-api",
+			TTL:      24 * time.Hour,
+			Leeway:   30 * time.Second,
+		},
+		// Invite-only, so a fresh deployment is not open to anyone on the network
+		Auth: Auth{
+			RegistrationMode:    "invite",
+			DefaultOrganization: "default",
+			TOTPIssuer:          "Inventory API",
+		},
+		OIDC: OIDC{
+			RoleClaim: "groups",
+		},
+		Mail: Mail{
+			SMTPPort: 587,
+		},
+		CORS: CORS{
+			MaxAge: 5 * time.Minute,
+		},
+		RateLimit: RateLimit{
+			Window: time.Minute,
+		},
+		Metrics: Metrics{
+			LowStockThreshold: 10,
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			ServiceName: "inventory-api",
+		},
+		ReservationSweepInterval: time.Minute,
+	}
+}
+
+// Load builds the configuration from the defaults, the YAML file at path (if
+// not empty), .env and the environment. The returned Config is filled in even
+// when validation fails, so it can still be printed.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("failed read config file: %w", err)
+		}
+		// Unknown keys are rejected so a typo doesn't silently fall back to the default
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, fmt.Errorf("failed parse config file %s: %w", path, err)
+		}
+	}
+
+	// .env is optional; missing it just means everything comes from the real environment
+	_ = godotenv.Load()
+
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem()); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Server.BaseURL == "" && cfg.Server.Port != "" {
+		cfg.Server.BaseURL = "http://localhost:" + cfg.Server.Port
+	}
+	cfg.Server.BaseURL = strings.TrimSuffix(cfg.Server.BaseURL, "/")
+	if cfg.OIDC.RedirectURL == "" && cfg.Server.BaseURL != "" {
+		cfg.OIDC.RedirectURL = cfg.Server.BaseURL + "/auth/oidc/callback"
+	}
+	if cfg.OIDC.Organization == "" {
+		cfg.OIDC.Organization = cfg.Auth.DefaultOrganization
+	}
+	if cfg.Auth.OperatorOrganization == "" {
+		cfg.Auth.OperatorOrganization = cfg.Auth.DefaultOrganization
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Validate reports every invalid setting at once, named by its environment variable.
+func (c Config) Validate() error {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+		return f.Tag.Get("env")
+	})
+
+	var problems []string
+	if err := validate.Struct(c); err != nil {
+		var errs validator.ValidationErrors
+		if !errors.As(err, &errs) {
+			return err
+		}
+		for _, e := range errs {
+			problems = append(problems, e.Field()+": "+describe(e))
+		}
+	}
+
+	if c.Auth.PasswordLoginDisabled && !c.OIDC.Enabled() {
+		problems = append(problems, "PASSWORD_LOGIN_DISABLED: requires OIDC_ISSUER_URL, otherwise nobody can log in")
+	}
+	if c.Mail.Driver == "smtp" && (c.Mail.SMTPHost == "" || c.Mail.From == "") {
+		problems = append(problems, "MAIL_DRIVER: smtp requires SMTP_HOST and MAIL_FROM")
+	}
+	if c.Database.MaxConns > 0 && c.Database.MinConns > c.Database.MaxConns {
+		problems = append(problems, "DB_MIN_CONNS: must not exceed DB_MAX_CONNS")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Redacted returns a copy that is safe to print: secret values are replaced
+// and passwords are removed from connection URLs.
+func (c Config) Redacted() Config {
+	redactStruct(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+// YAML renders the redacted configuration in the config file format.
+func (c Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c.Redacted())
+}
+
+func redactStruct(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field, f := v.Type().Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+			redactStruct(f)
+			continue
+		}
+
+		switch field.Tag.Get("secret") {
+		case "true":
+			if f.String() != "" {
+				f.SetString("REDACTED")
+			}
+		case "url":
+			// Keyword/value DSNs ("host=... password=...") cannot be cleaned, so hide them whole
+			if u, err := url.Parse(f.String()); err == nil && u.Scheme != "" {
+				f.SetString(u.Redacted())
+			} else if f.String() != "" {
+				f.SetString("REDACTED")
+			}
+		}
+	}
+}
+
+// describe turns a validation failure into a sentence.
+func describe(e validator.FieldError) string {
+	switch e.Tag() {
+	case "required":
+		return "is required"
+	case "required_with":
+		return "is required when " + e.Param() + " is set"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(e.Param(), " ", ", ")
+	case "gt":
+		return "must be greater than " + e.Param()
+	case "gte":
+		if e.Param() == "0" {
+			return "must not be negative"
+		}
+		return "must be at least " + e.Param()
+	case "lte":
+		return "must be at most " + e.Param()
+	case "url":
+		return "must be a URL"
+	case "numeric":
+		return "must be a number"
+	}
+	return "failed " + e.Tag()
+}
+
+// applyEnv overrides fields with the environment variable named in their env
+// tag, when it is set and not empty.
+func applyEnv(v reflect.Value) error {
+	var problems []string
+
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			field, f := v.Type().Field(i), v.Field(i)
+			key := field.Tag.Get("env")
+			if key == "" {
+				if field.Type.Kind() == reflect.Struct {
+					walk(f)
+				}
+				continue
+			}
+
+			raw, ok := os.LookupEnv(key)
+			if !ok || raw == "" {
+				continue
+			}
+			if err := setField(f, raw); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+			}
+		}
+	}
+	walk(v)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func setField(f reflect.Value, raw string) error {
+	if f.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, f.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		f.SetInt(n)
+	case reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		f.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
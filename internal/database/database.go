@@ -2,27 +2,114 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"inventory-api/internal/auth"
+	"inventory-api/internal/config"
 )
 
-func InitDB(connString string) (*pgxpool.Pool, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Backoff between startup connection attempts, doubling up to the cap
+const (
+	initialRetryDelay = 500 * time.Millisecond
+	maxRetryDelay     = 10 * time.Second
+)
 
-	dbPool, err := pgxpool.New(ctx, connString)
+// InitDB opens the pool described by cfg; zero values keep the pgxpool
+// defaults. While Postgres is unreachable it retries with exponential backoff
+// for up to cfg.ConnectMaxWait, so the API can start before the database.
+func InitDB(cfg config.Database) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create database pool: %w", err)
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
 	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	if cfg.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+	if cfg.ConnectTimeout > 0 {
+		poolConfig.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+	poolConfig.PrepareConn = setCurrentOrg
+	poolConfig.ConnConfig.Tracer = newQueryTracer()
 
-	err = dbPool.Ping(ctx)
+	dbPool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed ping to database: %w", err)
+		return nil, fmt.Errorf("failed to create database pool: %w", err)
+	}
+
+	if err := waitForDB(dbPool, cfg.ConnectTimeout, cfg.ConnectMaxWait); err != nil {
+		dbPool.Close()
+		return nil, err
 	}
 
 	slog.Info("Database connected successfully")
 	return dbPool, nil
 }
+
+// waitForDB pings until the database answers or maxWait has passed.
+func waitForDB(dbPool *pgxpool.Pool, attemptTimeout, maxWait time.Duration) error {
+	if attemptTimeout <= 0 {
+		attemptTimeout = 5 * time.Second
+	}
+	deadline := time.Now().Add(maxWait)
+	delay := initialRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), attemptTimeout)
+		err := dbPool.Ping(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		// Postgres answered but refused us (bad password, unknown database): waiting will not
+		// help, unless it is still starting up (57P03 cannot_connect_now)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code != "57P03" {
+			return fmt.Errorf("failed ping to database: %w", err)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("failed ping to database after %d attempts: %w", attempt, err)
+		}
+
+		wait := min(delay, remaining)
+		slog.Warn("Database not reachable yet, retrying", "attempt", attempt, "retry_in", wait.String(), "error", err)
+		time.Sleep(wait)
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// setCurrentOrg sets app.current_org on every acquired connection to the
+// organization of the request's principal, which the row-level security
+// policies compare against. Requests without a principal clear it.
+func setCurrentOrg(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	_, err := conn.Exec(ctx, "SELECT set_config('app.current_org', $1, false)", auth.OrganizationID(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed set current organization: %w", err)
+	}
+	return true, nil
+}
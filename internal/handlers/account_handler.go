@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+
+	"inventory-api/internal/logging"
+	"inventory-api/internal/mailer"
+	"inventory-api/internal/repository"
+)
+
+const (
+	verificationTokenTTL  = 48 * time.Hour
+	passwordResetTokenTTL = time.Hour
+)
+
+type TokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req TokenRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	userID, ok := h.consumeToken(w, r.Context(), req.Token, repository.TokenEmailVerification)
+	if !ok {
+		return
+	}
+
+	if err := h.Repo.MarkEmailVerified(r.Context(), userID); err != nil {
+		http.Error(w, "Failed verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Email verified successfully",
+	})
+}
+
+// ResendVerification always answers the same way so it cannot be used to probe
+// which emails are registered.
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req EmailRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	user, err := h.Repo.GetUserByEmail(r.Context(), req.Email)
+	if err == nil && user.EmailVerifiedAt == nil {
+		if err := h.sendVerificationEmail(r.Context(), user); err != nil {
+			logging.FromContext(r.Context()).Error("Failed send verification email", "user_id", user.ID, "error", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If the account exists and is unverified, a verification email has been sent",
+	})
+}
+
+func (h *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req EmailRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	if user, err := h.Repo.GetUserByEmail(r.Context(), req.Email); err == nil {
+		link, err := h.tokenLink(r.Context(), user.ID, repository.TokenPasswordReset, passwordResetTokenTTL, "/reset-password")
+		if err == nil {
+			err = h.Mailer.Send(r.Context(), mailer.Message{
+				To:      user.Email,
+				Subject: "Reset your password",
+				Body:    fmt.Sprintf("Use the link below within 1 hour to choose a new password:\n\n%s\n\nIf you did not ask for this, ignore this email.", link),
+			})
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Failed send password reset email", "user_id", user.ID, "error", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If the account exists, a password reset email has been sent",
+	})
+}
+
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	userID, ok := h.consumeToken(w, r.Context(), req.Token, repository.TokenPasswordReset)
+	if !ok {
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		http.Error(w, "Gagal memproses password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.Repo.UpdatePassword(r.Context(), userID, string(hashedPassword)); err != nil {
+		http.Error(w, "Failed reset password", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password reset successfully",
+	})
+}
+
+// GetMe returns the logged-in user with their role in the current organization.
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	member, err := h.Orgs.GetMember(r.Context(), currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to fetch account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": member,
+	})
+}
+
+// ChangePassword requires the current password so a stolen session token alone
+// cannot take over the account.
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req ChangePasswordRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	user, err := h.Repo.GetUserByID(r.Context(), currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to fetch account", http.StatusInternalServerError)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		http.Error(w, "Current password is incorrect", http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		http.Error(w, "Gagal memproses password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.Repo.UpdatePassword(r.Context(), user.ID, string(hashedPassword)); err != nil {
+		http.Error(w, "Failed change password", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password changed successfully",
+	})
+}
+
+func (h *UserHandler) sendVerificationEmail(ctx context.Context, user *repository.User) error {
+	link, err := h.tokenLink(ctx, user.ID, repository.TokenEmailVerification, verificationTokenTTL, "/verify-email")
+	if err != nil {
+		return err
+	}
+
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body:    fmt.Sprintf("Welcome! Confirm your email address with the link below:\n\n%s", link),
+	})
+}
+
+// tokenLink creates a single-use token and returns BaseURL+path with it as ?token=.
+func (h *UserHandler) tokenLink(ctx context.Context, userID, purpose string, ttl time.Duration, path string) (string, error) {
+	id, err := h.Repo.CreateUserToken(ctx, userID, purpose, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	return h.BaseURL + path + "?token=" + url.QueryEscape(h.Tokens.Sign(id)), nil
+}
+
+// consumeToken verifies the signature and burns the token, writing a 400 on failure.
+func (h *UserHandler) consumeToken(w http.ResponseWriter, ctx context.Context, token, purpose string) (string, bool) {
+	id, err := h.Tokens.Verify(token)
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return "", false
+	}
+
+	userID, err := h.Repo.ConsumeUserToken(ctx, id, purpose)
+	if err != nil {
+		if err.Error() == "token invalid or expired" {
+			http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed verify token", http.StatusInternalServerError)
+		}
+		return "", false
+	}
+	return userID, true
+}
+
+// decodeAndValidate decodes the JSON body into v and validates it, writing a 400 on failure.
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return false
+	}
+
+	if err := validator.New().Struct(v); err != nil {
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
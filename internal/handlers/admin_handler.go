@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"inventory-api/internal/auth"
+	"inventory-api/internal/repository"
+)
+
+type AdminHandler struct {
+	LoginAttempts *repository.LoginAttemptRepository
+	Members       *repository.OrganizationRepository
+	// OperatorOrganization is the slug of the organization whose admins may
+	// also see and clear per-IP lockouts, which span every organization.
+	OperatorOrganization string
+}
+
+// isOperator reports whether the caller is an admin of the operator organization.
+func (h *AdminHandler) isOperator(ctx context.Context) (bool, error) {
+	if h.OperatorOrganization == "" {
+		return false, nil
+	}
+
+	org, err := h.Members.GetOrganizationBySlug(ctx, h.OperatorOrganization)
+	if err != nil {
+		if err.Error() == "organization not found" {
+			return false, nil
+		}
+		return false, err
+	}
+	return org.ID == auth.OrganizationID(ctx), nil
+}
+
+// GetLockouts lists failed-login counters for the organization's members
+// (and per-IP counters for operators); ?locked=true shows only active lockouts.
+func (h *AdminHandler) GetLockouts(w http.ResponseWriter, r *http.Request) {
+	lockedOnly := r.URL.Query().Get("locked") == "true"
+
+	operator, err := h.isOperator(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch lockouts", http.StatusInternalServerError)
+		return
+	}
+
+	failures, err := h.LoginAttempts.GetTenantFailures(r.Context(), lockedOnly, operator)
+	if err != nil {
+		http.Error(w, "Failed to fetch lockouts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": failures,
+	})
+}
+
+// Unlock clears the counter for /admin/lockouts/{scope}/{key}, e.g. /admin/lockouts/email/jane@example.com.
+func (h *AdminHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	scope := chi.URLParam(r, "scope")
+	if scope != repository.LoginScopeEmail && scope != repository.LoginScopeIP {
+		http.Error(w, "scope must be email or ip", http.StatusBadRequest)
+		return
+	}
+
+	operator, err := h.isOperator(r.Context())
+	if err != nil {
+		http.Error(w, "Failed unlock", http.StatusInternalServerError)
+		return
+	}
+	if scope == repository.LoginScopeIP && !operator {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	cleared, err := h.LoginAttempts.ClearTenantFailure(r.Context(), scope, chi.URLParam(r, "key"), operator)
+	if err != nil {
+		http.Error(w, "Failed unlock", http.StatusInternalServerError)
+		return
+	}
+	if !cleared {
+		http.Error(w, "Lockout not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Unlocked successfully",
+	})
+}
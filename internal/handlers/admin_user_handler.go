@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"inventory-api/internal/repository"
+)
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin staff"`
+}
+
+// GetUsers lists members of the admin's organization. Filters: ?q= (email),
+// ?role= and ?status=active|deactivated, plus ?page= and ?per_page=.
+func (h *AdminHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	page, perPage, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := repository.MemberFilter{
+		Query:   q.Get("q"),
+		Role:    q.Get("role"),
+		Status:  q.Get("status"),
+		Page:    page,
+		PerPage: perPage,
+	}
+	if filter.Status != "" && filter.Status != "active" && filter.Status != "deactivated" {
+		http.Error(w, "status must be active or deactivated", http.StatusBadRequest)
+		return
+	}
+
+	members, total, err := h.Members.GetMembers(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": members,
+		"meta": PageMeta{Page: page, PerPage: perPage, Total: total},
+	})
+}
+
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	member, err := h.Members.GetMember(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeMemberError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": member,
+	})
+}
+
+func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	var req UpdateRoleRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	if err := h.Members.UpdateMemberRole(r.Context(), chi.URLParam(r, "id"), req.Role); err != nil {
+		writeMemberError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Role updated successfully",
+	})
+}
+
+// DeactivateUser blocks the user's logins, tokens and API keys in this
+// organization without deleting anything they created.
+func (h *AdminHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserActive(w, r, false, "User deactivated successfully")
+}
+
+func (h *AdminHandler) ActivateUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserActive(w, r, true, "User activated successfully")
+}
+
+func (h *AdminHandler) setUserActive(w http.ResponseWriter, r *http.Request, active bool, message string) {
+	id := chi.URLParam(r, "id")
+	if !active && id == currentUserID(r) {
+		http.Error(w, "You cannot deactivate your own account", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Members.SetMemberActive(r.Context(), id, active); err != nil {
+		writeMemberError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": message,
+	})
+}
+
+// DeleteUser removes the user from this organization; the account itself is
+// deleted once it belongs to no organization.
+func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == currentUserID(r) {
+		http.Error(w, "You cannot delete your own account", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Members.RemoveMember(r.Context(), id); err != nil {
+		writeMemberError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "User deleted successfully",
+	})
+}
+
+func writeMemberError(w http.ResponseWriter, err error) {
+	switch err.Error() {
+	case "member not found":
+		http.Error(w, "User not found", http.StatusNotFound)
+	case "organization needs an active admin":
+		http.Error(w, "The organization must keep at least one active admin", http.StatusConflict)
+	default:
+		http.Error(w, "Failed update user", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"inventory-api/internal/auth"
+	"inventory-api/internal/repository"
+)
+
+type APIKeyHandler struct {
+	Repo *repository.APIKeyRepository
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+	// ExpiresInDays is 0 for a key that never expires, at most 10 years.
+	ExpiresInDays int `json:"expires_in_days" validate:"gte=0,lte=3650"`
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !slices.Contains(repository.APIKeyScopes, scope) {
+			http.Error(w, fmt.Sprintf("Unknown scope %q", scope), http.StatusBadRequest)
+			return
+		}
+	}
+
+	key := repository.APIKey{
+		UserID: currentUserID(r),
+		Name:   req.Name,
+		Scopes: req.Scopes,
+	}
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+
+	if err := h.Repo.CreateAPIKey(r.Context(), &key, ttl); err != nil {
+		http.Error(w, "Failed create api key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "api key created, store the key now: it will not be shown again",
+		"data":    key,
+	})
+}
+
+// GetAPIKeys lists the caller's keys; admins can pass ?all=true to see every key.
+func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	if r.URL.Query().Get("all") == "true" && isAdmin(r) {
+		userID = ""
+	}
+
+	keys, err := h.Repo.GetAPIKeys(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch api keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": keys,
+	})
+}
+
+// RevokeAPIKey revokes one of the caller's keys; admins may revoke any key.
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	if isAdmin(r) {
+		userID = ""
+	}
+
+	err := h.Repo.RevokeAPIKey(r.Context(), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		if err.Error() == "api key not found" {
+			http.Error(w, "API key not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed revoke api key", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "API key revoked successfully",
+	})
+}
+
+func currentUserID(r *http.Request) string {
+	return auth.UserID(r.Context())
+}
+
+func isAdmin(r *http.Request) bool {
+	p, ok := auth.PrincipalFrom(r.Context())
+	return ok && p.HasRole(repository.RoleAdmin)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -12,7 +13,9 @@ import (
 )
 
 type CategoryHandler struct {
-	Repo *repository.CategoryRepository
+	Repo     *repository.CategoryRepository
+	Products *repository.ProductRepository
+	Tx       *repository.TxManager
 }
 
 func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
@@ -53,13 +56,31 @@ func (h *CategoryHandler) GetAllCategories(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+// DeleteCategory removes the category. With ?reassign_to=<category id> its
+// products move to that category in the same transaction; otherwise they are
+// left without a category.
 func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
+	reassignTo := r.URL.Query().Get("reassign_to")
 
-	err := h.Repo.DeleteCategory(r.Context(), id)
+	if reassignTo == id {
+		http.Error(w, "reassign_to must be a different category", http.StatusBadRequest)
+		return
+	}
+
+	err := h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		if reassignTo != "" {
+			if _, err := h.Products.ReassignCategory(ctx, id, reassignTo); err != nil {
+				return err
+			}
+		}
+		return h.Repo.DeleteCategory(ctx, id)
+	})
 	if err != nil {
 		if err.Error() == "category not found" {
 			http.Error(w, "Category not found", http.StatusNotFound)
+		} else if err.Error() == "target category not found" {
+			http.Error(w, "Target category not found", http.StatusBadRequest)
 		} else {
 			http.Error(w, "Gagal menghapus kategori", http.StatusInternalServerError)
 		}
@@ -2,16 +2,22 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"inventory-api/internal/phone"
 	"inventory-api/internal/repository"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 )
 
 type CustomerHandler struct {
-	Repo *repository.CustomerRepository
+	Repo      *repository.CustomerRepository
+	Addresses *repository.CustomerAddressRepository
+	// DefaultCountryCode is used to normalize national phone numbers (e.g. "62").
+	DefaultCountryCode string
 }
 
 func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
@@ -22,16 +28,16 @@ func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(customer); err != nil {
-		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+	if !h.normalizeAndValidate(w, &customer) {
 		return
 	}
 
 	err := h.Repo.CreateCustomer(r.Context(), &customer)
 
 	if err != nil {
-		http.Error(w, "Failed store data (email might be duplicate)", http.StatusInternalServerError)
+		if !writeDuplicateCustomer(w, err) {
+			http.Error(w, "Failed store data", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -45,7 +51,23 @@ func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *CustomerHandler) GetAllCustomers(w http.ResponseWriter, r *http.Request) {
-	customers, err := h.Repo.GetAllCustomers(r.Context())
+	page, perPage, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := repository.CustomerFilter{
+		Query:   q.Get("q"),
+		Name:    q.Get("name"),
+		Email:   q.Get("email"),
+		Phone:   q.Get("phone"),
+		Page:    page,
+		PerPage: perPage,
+	}
+
+	customers, total, err := h.Repo.GetAllCustomers(r.Context(), filter)
 	if err != nil {
 		http.Error(w, "Failed to fetch customers", http.StatusInternalServerError)
 		return
@@ -54,5 +76,253 @@ func (h *CustomerHandler) GetAllCustomers(w http.ResponseWriter, r *http.Request
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"data": customers,
+		"meta": PageMeta{Page: page, PerPage: perPage, Total: total},
+	})
+}
+
+func (h *CustomerHandler) GetCustomerByID(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	customer, err := h.Repo.GetCustomerByID(r.Context(), id)
+	if err != nil {
+		if err.Error() == "customer not found" {
+			http.Error(w, "Customer not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch customer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	customer.Addresses, err = h.Addresses.GetAddressesByCustomer(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to fetch addresses", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": customer,
+	})
+}
+
+func (h *CustomerHandler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var customer repository.Customer
+	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !h.normalizeAndValidate(w, &customer) {
+		return
+	}
+
+	err := h.Repo.UpdateCustomer(r.Context(), id, &customer)
+	if err != nil {
+		if err.Error() == "customer not found" {
+			http.Error(w, "Customer not found", http.StatusNotFound)
+		} else if !writeDuplicateCustomer(w, err) {
+			http.Error(w, "Failed update customer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "customer updated successfully",
+		"data":    customer,
+	})
+}
+
+// normalizeAndValidate rewrites the phone number to E.164 and validates the
+// customer, writing a 400 response and returning false on failure.
+func (h *CustomerHandler) normalizeAndValidate(w http.ResponseWriter, customer *repository.Customer) bool {
+	if customer.Phone != "" {
+		normalized, err := phone.Normalize(customer.Phone, h.DefaultCountryCode)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+			return false
+		}
+		customer.Phone = normalized
+	}
+
+	if err := validator.New().Struct(customer); err != nil {
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writeDuplicateCustomer writes a 409 with the conflicting customer ID when err
+// is a DuplicateCustomerError and reports whether it did.
+func writeDuplicateCustomer(w http.ResponseWriter, err error) bool {
+	var dup *repository.DuplicateCustomerError
+	if !errors.As(err, &dup) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":                 "customer with this email already exists",
+		"conflicting_customer_id": dup.ExistingID,
+	})
+	return true
+}
+
+func (h *CustomerHandler) GetAddresses(w http.ResponseWriter, r *http.Request) {
+	addresses, err := h.Addresses.GetAddressesByCustomer(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Failed to fetch addresses", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": addresses,
+	})
+}
+
+func (h *CustomerHandler) GetAddress(w http.ResponseWriter, r *http.Request) {
+	address, err := h.Addresses.GetAddressByID(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "addressID"))
+	if err != nil {
+		if err.Error() == "address not found" {
+			http.Error(w, "Address not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch address", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": address,
+	})
+}
+
+func (h *CustomerHandler) CreateAddress(w http.ResponseWriter, r *http.Request) {
+	var address repository.CustomerAddress
+
+	if err := json.NewDecoder(r.Body).Decode(&address); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := validator.New().Struct(address); err != nil {
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	address.CustomerID = chi.URLParam(r, "id")
+	if err := h.Addresses.CreateAddress(r.Context(), &address); err != nil {
+		if err.Error() == "customer not found" {
+			http.Error(w, "Customer not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed store address", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "address created successfully",
+		"data":    address,
+	})
+}
+
+func (h *CustomerHandler) UpdateAddress(w http.ResponseWriter, r *http.Request) {
+	var address repository.CustomerAddress
+
+	if err := json.NewDecoder(r.Body).Decode(&address); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := validator.New().Struct(address); err != nil {
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	address.ID = chi.URLParam(r, "addressID")
+	address.CustomerID = chi.URLParam(r, "id")
+	if err := h.Addresses.UpdateAddress(r.Context(), &address); err != nil {
+		if err.Error() == "address not found" {
+			http.Error(w, "Address not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed update address", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "address updated successfully",
+		"data":    address,
+	})
+}
+
+func (h *CustomerHandler) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	err := h.Addresses.DeleteAddress(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "addressID"))
+	if err != nil {
+		if err.Error() == "address not found" {
+			http.Error(w, "Address not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed delete address", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Address deleted successfully",
+	})
+}
+
+type MergeCustomerRequest struct {
+	SourceID string `json:"source_id" validate:"required,uuid"`
+}
+
+// MergeCustomers folds the customer given as source_id into the customer in the URL.
+func (h *CustomerHandler) MergeCustomers(w http.ResponseWriter, r *http.Request) {
+	targetID := chi.URLParam(r, "id")
+
+	var req MergeCustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if req.SourceID == targetID {
+		http.Error(w, "Cannot merge a customer into itself", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Repo.MergeCustomers(r.Context(), targetID, req.SourceID); err != nil {
+		if err.Error() == "customer not found" {
+			http.Error(w, "Customer not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed merge customers", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	customer, err := h.Repo.GetCustomerByID(r.Context(), targetID)
+	if err != nil {
+		http.Error(w, "Failed to fetch merged customer", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "customers merged successfully",
+		"data":    customer,
 	})
 }
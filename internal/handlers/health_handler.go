@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"inventory-api/internal/logging"
+	"inventory-api/internal/repository"
+)
+
+const readinessTimeout = 2 * time.Second
+
+type HealthHandler struct {
+	Repo *repository.HealthRepository
+	// SchemaVersion is the migration version this build expects.
+	SchemaVersion uint
+}
+
+type ComponentStatus struct {
+	Status string `json:"status"`
+	// Error is a fixed description; driver errors are only logged, since the
+	// probe is public and they can reveal hosts and users.
+	Error string `json:"error,omitempty"`
+	// Version and Expected are only set for the migrations component.
+	Version  uint `json:"version,omitempty"`
+	Expected uint `json:"expected,omitempty"`
+}
+
+type HealthResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+// Healthz is the liveness probe: it only says the process is serving requests,
+// so a database outage does not get the instance restarted.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, HealthResponse{Status: "ok"})
+}
+
+// Readyz is the readiness probe: the database (and the read replica, when
+// configured) must answer and the schema must be at least the version this
+// build expects and not left dirty by a failed migration. A newer schema is
+// accepted so old instances keep serving while a rolling deploy migrates ahead
+// of them.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	resp := HealthResponse{Status: "ok", Components: map[string]ComponentStatus{}}
+
+	// Listings read from the replica, so without it the instance cannot serve them
+	if h.Repo.HasReplica() {
+		if err := h.Repo.PingReplica(ctx); err != nil {
+			logging.FromContext(ctx).Error("Readiness check failed", "component", "replica", "error", err)
+			resp.Status = "unavailable"
+			resp.Components["replica"] = ComponentStatus{Status: "down"}
+		} else {
+			resp.Components["replica"] = ComponentStatus{Status: "up"}
+		}
+	}
+
+	if err := h.Repo.Ping(ctx); err != nil {
+		logging.FromContext(ctx).Error("Readiness check failed", "component", "database", "error", err)
+		resp.Status = "unavailable"
+		resp.Components["database"] = ComponentStatus{Status: "down"}
+		resp.Components["migrations"] = ComponentStatus{Status: "unknown", Expected: h.SchemaVersion}
+		writeHealth(w, resp)
+		return
+	}
+	resp.Components["database"] = ComponentStatus{Status: "up"}
+
+	migrations := ComponentStatus{Status: "up", Expected: h.SchemaVersion}
+	version, dirty, err := h.Repo.GetSchemaVersion(ctx)
+	switch {
+	case err != nil:
+		logging.FromContext(ctx).Error("Readiness check failed", "component", "migrations", "error", err)
+		migrations.Status = "down"
+	case dirty:
+		migrations.Status, migrations.Error = "down", "last migration failed, schema is dirty"
+	case version < h.SchemaVersion:
+		migrations.Status, migrations.Error = "down", "schema is behind, run the migrations"
+	}
+	migrations.Version = version
+	resp.Components["migrations"] = migrations
+
+	if migrations.Status != "up" {
+		resp.Status = "unavailable"
+	}
+
+	writeHealth(w, resp)
+}
+
+func writeHealth(w http.ResponseWriter, resp HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if resp.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"inventory-api/internal/logging"
+	"inventory-api/internal/mailer"
+	"inventory-api/internal/repository"
+	"inventory-api/internal/tokens"
+)
+
+const defaultInvitationTTL = 7 * 24 * time.Hour
+
+type InvitationHandler struct {
+	Repo   *repository.InvitationRepository
+	Mailer mailer.Mailer
+	Tokens *tokens.Signer
+	// BaseURL is the public URL used in links sent by email.
+	BaseURL string
+}
+
+type CreateInvitationRequest struct {
+	Email         string `json:"email" validate:"required,email"`
+	Role          string `json:"role" validate:"required,oneof=admin staff"`
+	ExpiresInDays int    `json:"expires_in_days" validate:"gte=0,lte=30"`
+}
+
+// CreateInvitation invites an email into the admin's organization and mails the
+// registration link. The token is also returned once so it can be shared by hand.
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	var req CreateInvitationRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	ttl := defaultInvitationTTL
+	if req.ExpiresInDays > 0 {
+		ttl = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+
+	inv := repository.Invitation{
+		Email: req.Email,
+		Role:  req.Role,
+	}
+	if err := h.Repo.CreateInvitation(r.Context(), &inv, ttl); err != nil {
+		http.Error(w, "Failed create invitation", http.StatusInternalServerError)
+		return
+	}
+
+	token := h.Tokens.Sign(inv.ID)
+	link := h.BaseURL + "/accept-invite?token=" + url.QueryEscape(token)
+
+	// Gagal kirim email tidak membatalkan undangan, token tetap bisa dibagikan manual
+	err := h.Mailer.Send(r.Context(), mailer.Message{
+		To:      inv.Email,
+		Subject: "You have been invited to the Inventory API",
+		Body:    fmt.Sprintf("You have been invited as %s. Create your account with the link below before %s:\n\n%s", inv.Role, inv.ExpiresAt.Format(time.RFC1123), link),
+	})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed send invitation email", "invitation_id", inv.ID, "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Invitation created",
+		"data":    inv,
+		"token":   token,
+	})
+}
+
+// GetInvitations lists invitations that are still pending (including expired ones).
+func (h *InvitationHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.Repo.GetPendingInvitations(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch invitations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": invitations,
+	})
+}
+
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	err := h.Repo.RevokeInvitation(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if err.Error() == "invitation not found" {
+			http.Error(w, "Invitation not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed revoke invitation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Invitation revoked successfully",
+	})
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"inventory-api/internal/auth"
+)
+
+type JWKSHandler struct {
+	Keys *auth.KeySet
+}
+
+// GetJWKS publishes the public keys other services use to verify our tokens.
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.Keys.JWKS())
+}
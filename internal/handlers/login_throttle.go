@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"inventory-api/internal/logging"
+	"inventory-api/internal/repository"
+)
+
+// LoginPolicy describes how failed logins for one scope slow down and lock out.
+type LoginPolicy struct {
+	// DelayAfter is the number of failures before progressive delays start.
+	DelayAfter int
+	// MaxFailures is the number of failures that triggers a lockout.
+	MaxFailures  int
+	LockDuration time.Duration
+	MaxDelay     time.Duration
+	// Window is how long a failure is remembered when no further failures follow.
+	Window time.Duration
+}
+
+var (
+	emailLoginPolicy = LoginPolicy{DelayAfter: 3, MaxFailures: 10, LockDuration: 15 * time.Minute, MaxDelay: 30 * time.Second, Window: time.Hour}
+	ipLoginPolicy    = LoginPolicy{DelayAfter: 10, MaxFailures: 50, LockDuration: 15 * time.Minute, MaxDelay: 30 * time.Second, Window: time.Hour}
+)
+
+// retryAfter returns how long the client must wait before the next attempt:
+// the remaining lockout, or an exponential delay (1s, 2s, 4s, ... up to
+// MaxDelay) counted from the last failure once DelayAfter is reached.
+func (p LoginPolicy) retryAfter(f repository.LoginFailure, now time.Time) time.Duration {
+	if f.LockedUntil != nil {
+		if f.LockedUntil.After(now) {
+			return f.LockedUntil.Sub(now)
+		}
+		return 0
+	}
+
+	if f.FailedCount < p.DelayAfter || now.Sub(f.LastFailedAt) > p.Window {
+		return 0
+	}
+
+	delay := p.MaxDelay
+	if shift := f.FailedCount - p.DelayAfter; shift < 16 {
+		delay = min(time.Second<<shift, p.MaxDelay)
+	}
+
+	return max(f.LastFailedAt.Add(delay).Sub(now), 0)
+}
+
+type loginThrottleKey struct {
+	scope  string
+	key    string
+	policy LoginPolicy
+}
+
+func loginThrottleKeys(email, ip string) []loginThrottleKey {
+	return []loginThrottleKey{
+		{repository.LoginScopeEmail, strings.ToLower(email), emailLoginPolicy},
+		{repository.LoginScopeIP, ip, ipLoginPolicy},
+	}
+}
+
+// reserveLoginAttempt counts the attempt as a failure for the email and the
+// client IP before the credentials are checked, so parallel guesses cannot all
+// slip past the throttle. It writes a 429 with Retry-After (or a 500) and
+// returns false when either must wait; nothing stays counted in that case.
+// Call releaseLoginAttempt once the credentials are valid.
+func (h *UserHandler) reserveLoginAttempt(w http.ResponseWriter, ctx context.Context, email, ip string) bool {
+	now := time.Now()
+	keys := loginThrottleKeys(email, ip)
+
+	for i, k := range keys {
+		wait, err := h.Attempts.ReserveAttempt(ctx, k.scope, k.key, k.policy.MaxFailures, k.policy.LockDuration, k.policy.Window,
+			func(f repository.LoginFailure) time.Duration { return k.policy.retryAfter(f, now) })
+		if err == nil && wait == 0 {
+			continue
+		}
+
+		h.releaseLoginKeys(ctx, keys[:i])
+		if err != nil {
+			http.Error(w, "Failed check login attempts", http.StatusInternalServerError)
+			return false
+		}
+
+		seconds := int((wait + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// releaseLoginAttempt gives back the failure reserved for a login whose
+// credentials turned out to be valid.
+func (h *UserHandler) releaseLoginAttempt(ctx context.Context, email, ip string) {
+	h.releaseLoginKeys(ctx, loginThrottleKeys(email, ip))
+}
+
+func (h *UserHandler) releaseLoginKeys(ctx context.Context, keys []loginThrottleKey) {
+	for _, k := range keys {
+		if err := h.Attempts.ReleaseAttempt(ctx, k.scope, k.key, k.policy.MaxFailures); err != nil {
+			logging.FromContext(ctx).Error("Failed release login attempt", "error", err)
+		}
+	}
+}
+
+// clientIP returns the host part of RemoteAddr. Behind a proxy, enable
+// TRUST_PROXY_HEADERS so RemoteAddr is taken from X-Forwarded-For.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
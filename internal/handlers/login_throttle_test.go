@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"inventory-api/internal/repository"
+)
+
+func TestLoginPolicyRetryAfter(t *testing.T) {
+	policy := LoginPolicy{DelayAfter: 3, MaxFailures: 10, LockDuration: 15 * time.Minute, MaxDelay: 30 * time.Second, Window: time.Hour}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		ts := now.Add(d)
+		return &ts
+	}
+
+	tests := []struct {
+		name        string
+		count       int
+		lastFailed  time.Duration
+		lockedUntil *time.Time
+		want        time.Duration
+	}{
+		{"no failures", 0, 0, nil, 0},
+		{"below delay threshold", 2, 0, nil, 0},
+		{"first delay", 3, 0, nil, time.Second},
+		{"delay doubles", 4, 0, nil, 2 * time.Second},
+		{"delay doubles again", 5, 0, nil, 4 * time.Second},
+		{"delay capped", 8, 0, nil, 30 * time.Second},
+		{"large count capped", 100, 0, nil, 30 * time.Second},
+		{"delay partly elapsed", 3, -400 * time.Millisecond, nil, 600 * time.Millisecond},
+		{"delay elapsed", 4, -5 * time.Second, nil, 0},
+		{"outside window", 9, -2 * time.Hour, nil, 0},
+		{"locked", 10, 0, at(5 * time.Minute), 5 * time.Minute},
+		{"lock expired", 10, -20 * time.Minute, at(-time.Minute), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := repository.LoginFailure{
+				FailedCount:  tt.count,
+				LastFailedAt: now.Add(tt.lastFailed),
+				LockedUntil:  tt.lockedUntil,
+			}
+			if got := policy.retryAfter(f, now); got != tt.want {
+				t.Errorf("retryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"inventory-api/internal/repository"
+)
+
+type OrganizationHandler struct {
+	Repo *repository.OrganizationRepository
+}
+
+// GetMyOrganizations lists the organizations the caller can sign in to, with their role in each.
+func (h *OrganizationHandler) GetMyOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.Repo.GetOrganizationsForUser(r.Context(), currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to fetch organizations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": orgs,
+	})
+}
+
+// CreateOrganization creates a new tenant with the caller as its admin. Log in
+// again with its slug to work inside it.
+func (h *OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var org repository.Organization
+	if err := json.NewDecoder(r.Body).Decode(&org); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	org.Slug = strings.ToLower(strings.TrimSpace(org.Slug))
+	if err := validator.New().Struct(org); err != nil {
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Repo.CreateOrganization(r.Context(), &org, currentUserID(r)); err != nil {
+		if err.Error() == "organization slug already taken" {
+			http.Error(w, "Organization slug already taken", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed create organization", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Organization created",
+		"data":    org,
+	})
+}
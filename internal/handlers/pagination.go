@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+	// maxPage keeps (page-1)*per_page, the SQL OFFSET, within an int32
+	maxPage = math.MaxInt32 / maxPerPage
+)
+
+type PageMeta struct {
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+	Total   int `json:"total"`
+}
+
+// parsePagination reads ?page= and ?per_page= with defaults of 1 and 20.
+func parsePagination(r *http.Request) (page, perPage int, err error) {
+	page, perPage = 1, defaultPerPage
+
+	if v := r.URL.Query().Get("page"); v != "" {
+		page, err = strconv.Atoi(v)
+		if err != nil || page < 1 || page > maxPage {
+			return 0, 0, fmt.Errorf("page must be between 1 and %d", maxPage)
+		}
+	}
+
+	if v := r.URL.Query().Get("per_page"); v != "" {
+		perPage, err = strconv.Atoi(v)
+		if err != nil || perPage < 1 || perPage > maxPerPage {
+			return 0, 0, fmt.Errorf("per_page must be between 1 and %d", maxPerPage)
+		}
+	}
+
+	return page, perPage, nil
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query       string
+		wantPage    int
+		wantPerPage int
+		wantErr     bool
+	}{
+		{"", 1, defaultPerPage, false},
+		{"page=3&per_page=50", 3, 50, false},
+		{"page=21474836&per_page=100", 21474836, 100, false},
+		{"page=0", 0, 0, true},
+		{"page=-1", 0, 0, true},
+		{"page=abc", 0, 0, true},
+		{"page=21474837", 0, 0, true},
+		{"page=9223372036854775807", 0, 0, true},
+		{"per_page=0", 0, 0, true},
+		{"per_page=101", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/customers?"+tt.query, nil)
+			page, perPage, err := parsePagination(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePagination(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if page != tt.wantPage || perPage != tt.wantPerPage {
+				t.Errorf("parsePagination(%q) = (%d, %d), want (%d, %d)", tt.query, page, perPage, tt.wantPage, tt.wantPerPage)
+			}
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -12,7 +15,9 @@ import (
 )
 
 type ProductHandler struct {
-	Repo *repository.ProductRepository
+	Repo      *repository.ProductRepository
+	Movements *repository.StockMovementRepository
+	Tx        *repository.TxManager
 }
 
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
@@ -34,10 +39,28 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.Repo.CreateProduct(r.Context(), &product)
+	// Opening stock goes into the ledger together with the product, so the
+	// movement history always adds up to the quantity
+	err := h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		if err := h.Repo.CreateProduct(ctx, &product); err != nil {
+			return err
+		}
+		if product.Quantity == 0 {
+			return nil
+		}
+		return h.Movements.CreateMovement(ctx, &repository.StockMovement{
+			ProductID:      product.ID,
+			QuantityChange: product.Quantity,
+			Reason:         repository.MovementInitialStock,
+		})
+	})
 
 	if err != nil {
-		http.Error(w, "Failed store data", http.StatusInternalServerError)
+		if err.Error() == "category not found" {
+			http.Error(w, "Category not found", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed store data", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -63,6 +86,35 @@ func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		http.Error(w, "Query parameter q is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			http.Error(w, "limit must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.Repo.SearchProducts(r.Context(), q, limit)
+	if err != nil {
+		http.Error(w, "Failed to search products", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": results,
+	})
+}
+
 func (h *ProductHandler) GetProductByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
@@ -78,6 +130,21 @@ func (h *ProductHandler) GetProductByID(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+func (h *ProductHandler) GetProductMovements(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	movements, err := h.Movements.GetMovementsByProduct(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to fetch stock movements", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": movements,
+	})
+}
+
 func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
@@ -96,9 +163,12 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 
 	err := h.Repo.UpdateProduct(r.Context(), id, &product)
 	if err != nil {
-		if err.Error() == "product not found" {
+		switch err.Error() {
+		case "product not found":
 			http.Error(w, "Product not found", http.StatusNotFound)
-		} else {
+		case "quantity below reserved":
+			http.Error(w, "Quantity cannot be lower than the reserved stock", http.StatusConflict)
+		default:
 			http.Error(w, "Gagal update", http.StatusInternalServerError)
 		}
 		return
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+
+	"inventory-api/internal/repository"
+)
+
+// defaultReservationTTL is used when the client does not send ttl_seconds (24 hours).
+const defaultReservationTTL = 24 * 60 * 60
+
+type ReservationHandler struct {
+	Repo *repository.ReservationRepository
+}
+
+func (h *ReservationHandler) CreateReservation(w http.ResponseWriter, r *http.Request) {
+	var reservation repository.Reservation
+
+	if err := json.NewDecoder(r.Body).Decode(&reservation); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := validator.New().Struct(reservation); err != nil {
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if reservation.TTLSeconds == 0 {
+		reservation.TTLSeconds = defaultReservationTTL
+	}
+
+	err := h.Repo.CreateReservation(r.Context(), &reservation)
+	if err != nil {
+		switch err.Error() {
+		case "product not found":
+			http.Error(w, "Product not found", http.StatusNotFound)
+		case "customer not found":
+			http.Error(w, "Customer not found", http.StatusNotFound)
+		case "insufficient stock":
+			http.Error(w, "Insufficient available stock", http.StatusConflict)
+		default:
+			http.Error(w, "Failed store data", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "reservation created successfully",
+		"data":    reservation,
+	})
+}
+
+func (h *ReservationHandler) GetAllReservations(w http.ResponseWriter, r *http.Request) {
+	productID := r.URL.Query().Get("product_id")
+	activeOnly := r.URL.Query().Get("active") == "true"
+
+	reservations, err := h.Repo.GetAllReservations(r.Context(), productID, activeOnly)
+	if err != nil {
+		http.Error(w, "Failed to fetch reservations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": reservations,
+	})
+}
+
+func (h *ReservationHandler) ReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.Repo.ReleaseReservation(r.Context(), id)
+	if err != nil {
+		if err.Error() == "reservation not found" {
+			http.Error(w, "Active reservation not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed release reservation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Reservation released successfully",
+	})
+}
+
+func (h *ReservationHandler) FulfillReservation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.Repo.FulfillReservation(r.Context(), id)
+	if err != nil {
+		if err.Error() == "reservation not found" {
+			http.Error(w, "Active reservation not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed fulfill reservation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Reservation fulfilled, stock deducted",
+	})
+}
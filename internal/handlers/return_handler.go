@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+
+	"inventory-api/internal/repository"
+)
+
+type ReturnHandler struct {
+	Repo *repository.ReturnRepository
+}
+
+func (h *ReturnHandler) CreateReturn(w http.ResponseWriter, r *http.Request) {
+	var ret repository.Return
+
+	if err := json.NewDecoder(r.Body).Decode(&ret); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := validator.New().Struct(ret); err != nil {
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err := h.Repo.CreateReturn(r.Context(), &ret)
+	if err != nil {
+		switch err.Error() {
+		case "customer not found":
+			http.Error(w, "Customer not found", http.StatusNotFound)
+		case "product not found":
+			http.Error(w, "Product not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed process return", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "return processed successfully",
+		"data":    ret,
+	})
+}
+
+func (h *ReturnHandler) GetAllReturns(w http.ResponseWriter, r *http.Request) {
+	returns, err := h.Repo.GetAllReturns(r.Context(), r.URL.Query().Get("customer_id"))
+	if err != nil {
+		http.Error(w, "Failed to fetch returns", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": returns,
+	})
+}
+
+func (h *ReturnHandler) GetReturnByID(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	ret, err := h.Repo.GetReturnByID(r.Context(), id)
+	if err != nil {
+		if err.Error() == "return not found" {
+			http.Error(w, "Return not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch return", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": ret,
+	})
+}
+
+// ResolveQuarantine releases quarantined units of a return line back to stock
+// or scraps them.
+func (h *ReturnHandler) ResolveQuarantine(w http.ResponseWriter, r *http.Request) {
+	var res repository.QuarantineResolution
+
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := validator.New().Struct(res); err != nil {
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	movement, err := h.Repo.ResolveQuarantine(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "lineID"), res)
+	if err != nil {
+		switch err.Error() {
+		case "return line not found":
+			http.Error(w, "Return line not found", http.StatusNotFound)
+		case "return line not quarantined":
+			http.Error(w, "Return line is not quarantined", http.StatusConflict)
+		case "quantity exceeds quarantined":
+			http.Error(w, "Quantity exceeds the units still in quarantine", http.StatusConflict)
+		default:
+			http.Error(w, "Failed resolve quarantine", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "quarantine resolved successfully",
+		"data":    movement,
+	})
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"inventory-api/internal/logging"
+	"inventory-api/internal/repository"
+	"inventory-api/internal/sso"
+	"inventory-api/internal/tokens"
+)
+
+const (
+	ssoStateTTL    = 10 * time.Minute
+	ssoStateCookie = "oidc_state"
+	ssoCookiePath  = "/auth/oidc"
+)
+
+// SSOHandler signs users in through the company OpenID Connect provider.
+// Users are provisioned on first login into Organization and their role there
+// follows the ID token on every login.
+type SSOHandler struct {
+	Provider   *sso.Provider
+	Identities *repository.IdentityRepository
+	Users      *UserHandler
+	Tokens     *tokens.Signer
+	// Organization is the slug of the organization SSO users belong to.
+	Organization string
+	// SecureCookie marks the state cookie Secure, for deployments served over HTTPS.
+	SecureCookie bool
+}
+
+// Login starts the authorization-code flow. The signed state goes both in the
+// redirect and in a cookie, so the callback only completes in the browser that
+// started it.
+func (h *SSOHandler) Login(w http.ResponseWriter, r *http.Request) {
+	nonce, err := sso.NewNonce()
+	if err != nil {
+		http.Error(w, "Failed start login", http.StatusInternalServerError)
+		return
+	}
+
+	loginState := repository.LoginState{
+		Nonce:        nonce,
+		CodeVerifier: sso.NewVerifier(),
+	}
+	if err := h.Identities.CreateLoginState(r.Context(), &loginState, ssoStateTTL); err != nil {
+		http.Error(w, "Failed start login", http.StatusInternalServerError)
+		return
+	}
+
+	state := h.Tokens.Sign(loginState.ID)
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoStateCookie,
+		Value:    state,
+		Path:     ssoCookiePath,
+		MaxAge:   int(ssoStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   h.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, h.Provider.AuthCodeURL(state, loginState.Nonce, loginState.CodeVerifier), http.StatusFound)
+}
+
+// Callback finishes the flow and answers like LoginUser. The provider is
+// responsible for any second factor, so local 2FA is not asked again.
+func (h *SSOHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		http.Error(w, "Sign-in was cancelled or refused by the identity provider", http.StatusUnauthorized)
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(ssoStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		http.Error(w, "Invalid or expired login state", http.StatusUnauthorized)
+		return
+	}
+
+	// Cookie state sudah dipakai, hapus supaya tidak bisa diulang
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoStateCookie,
+		Path:     ssoCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	stateID, err := h.Tokens.Verify(state)
+	if err != nil {
+		http.Error(w, "Invalid or expired login state", http.StatusUnauthorized)
+		return
+	}
+
+	loginState, err := h.Identities.ConsumeLoginState(r.Context(), stateID)
+	if err != nil {
+		if err.Error() == "login state invalid or expired" {
+			http.Error(w, "Invalid or expired login state", http.StatusUnauthorized)
+		} else {
+			http.Error(w, "Failed verify login state", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	identity, err := h.Provider.Exchange(r.Context(), query.Get("code"), loginState.Nonce, loginState.CodeVerifier)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("SSO login rejected", "error", err)
+		http.Error(w, "Sign-in with the identity provider failed", http.StatusUnauthorized)
+		return
+	}
+
+	// Accounts are matched by email, so an address the provider has not verified could take over someone else's account
+	if identity.Email == "" || !identity.EmailVerified {
+		http.Error(w, "The identity provider did not supply a verified email", http.StatusForbidden)
+		return
+	}
+
+	user, err := h.Identities.ProvisionUser(r.Context(), repository.ExternalIdentity{
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		Email:   strings.ToLower(identity.Email),
+	}, h.Organization, identity.Role)
+	if err != nil {
+		if err.Error() == "organization needs an active admin" {
+			http.Error(w, "Your identity provider no longer grants you admin, but you are the organization's last active admin; promote another admin first", http.StatusConflict)
+			return
+		}
+		logging.FromContext(r.Context()).Error("Failed provision SSO user", "subject", identity.Subject, "error", err)
+		http.Error(w, "Failed provision account", http.StatusInternalServerError)
+		return
+	}
+
+	membership, ok := h.Users.loadMembership(w, r, user.ID, h.Organization)
+	if !ok {
+		return
+	}
+
+	h.Users.writeLoginToken(w, user, membership)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+
+	"inventory-api/internal/repository"
+)
+
+type StockCountHandler struct {
+	Repo *repository.StockCountRepository
+}
+
+type RecordCountsRequest struct {
+	Counts []repository.CountEntry `json:"counts" validate:"required,min=1,dive"`
+}
+
+func (h *StockCountHandler) CreateStockCount(w http.ResponseWriter, r *http.Request) {
+	var stockCount repository.StockCount
+
+	if err := json.NewDecoder(r.Body).Decode(&stockCount); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := validator.New().Struct(stockCount); err != nil {
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Repo.CreateStockCount(r.Context(), &stockCount); err != nil {
+		http.Error(w, "Failed create stock count", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "stock count started",
+		"data":    stockCount,
+	})
+}
+
+func (h *StockCountHandler) GetAllStockCounts(w http.ResponseWriter, r *http.Request) {
+	counts, err := h.Repo.GetAllStockCounts(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch stock counts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": counts,
+	})
+}
+
+func (h *StockCountHandler) GetStockCountByID(w http.ResponseWriter, r *http.Request) {
+	h.writeStockCount(w, r, false)
+}
+
+func (h *StockCountHandler) GetVariances(w http.ResponseWriter, r *http.Request) {
+	h.writeStockCount(w, r, true)
+}
+
+func (h *StockCountHandler) writeStockCount(w http.ResponseWriter, r *http.Request, varianceOnly bool) {
+	id := chi.URLParam(r, "id")
+
+	stockCount, err := h.Repo.GetStockCountByID(r.Context(), id, varianceOnly)
+	if err != nil {
+		if err.Error() == "stock count not found" {
+			http.Error(w, "Stock count not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch stock count", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": stockCount,
+	})
+}
+
+func (h *StockCountHandler) RecordCounts(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req RecordCountsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Repo.RecordCounts(r.Context(), id, req.Counts); err != nil {
+		writeStockCountError(w, err, "Failed record counts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Counts recorded successfully",
+	})
+}
+
+func (h *StockCountHandler) ApproveStockCount(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	movements, err := h.Repo.ApproveStockCount(r.Context(), id)
+	if err != nil {
+		writeStockCountError(w, err, "Failed approve stock count")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Stock count approved",
+		"data":    movements,
+	})
+}
+
+func (h *StockCountHandler) CancelStockCount(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := h.Repo.CancelStockCount(r.Context(), id); err != nil {
+		writeStockCountError(w, err, "Failed cancel stock count")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Stock count cancelled",
+	})
+}
+
+func writeStockCountError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case err.Error() == "stock count not found":
+		http.Error(w, "Stock count not found", http.StatusNotFound)
+	case err.Error() == "stock count not open":
+		http.Error(w, "Stock count is already closed", http.StatusConflict)
+	case err.Error() == "quantity below reserved":
+		http.Error(w, "Stock changed since the count was opened; the adjustment would leave a product below zero or below its reserved stock", http.StatusConflict)
+	case strings.HasPrefix(err.Error(), "sku "):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"inventory-api/internal/logging"
+	"inventory-api/internal/repository"
+	"inventory-api/internal/totp"
+)
+
+const (
+	mfaChallengeTTL = 5 * time.Minute
+	// totpSkew accepts codes one step either side of now for clock drift.
+	totpSkew = 1
+)
+
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	// Code is a 6-digit TOTP code or one of the recovery codes.
+	Code         string `json:"code" validate:"required"`
+	Organization string `json:"organization"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// LoginTwoFactor completes a login that LoginUser answered with mfa_required.
+// A wrong code counts as a failed login for the throttle but keeps the
+// challenge valid until it expires.
+func (h *UserHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req LoginTwoFactorRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	challengeID, err := h.Tokens.Verify(req.ChallengeToken)
+	if err != nil {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := h.Repo.PeekUserToken(r.Context(), challengeID, repository.TokenMFAChallenge)
+	if err != nil {
+		if err.Error() == "token invalid or expired" {
+			http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		} else {
+			http.Error(w, "Failed verify challenge", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	user, err := h.Repo.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch account", http.StatusInternalServerError)
+		return
+	}
+
+	ip := clientIP(r)
+	if !h.reserveLoginAttempt(w, r.Context(), user.Email, ip) {
+		return
+	}
+
+	ok, err := h.verifySecondFactor(r.Context(), user.ID, req.Code)
+	if err != nil {
+		h.releaseLoginAttempt(r.Context(), user.Email, ip)
+		http.Error(w, "Failed verify code", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+	h.releaseLoginAttempt(r.Context(), user.Email, ip)
+
+	// The challenge is single use; losing a race here means another request already used it
+	if _, err := h.Repo.ConsumeUserToken(r.Context(), challengeID, repository.TokenMFAChallenge); err != nil {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := h.Attempts.ClearFailure(r.Context(), repository.LoginScopeEmail, strings.ToLower(user.Email)); err != nil {
+		logging.FromContext(r.Context()).Error("Failed clear login failures", "error", err)
+	}
+
+	membership, ok := h.loadMembership(w, r, user.ID, req.Organization)
+	if !ok {
+		return
+	}
+
+	h.writeLoginToken(w, user, membership)
+}
+
+// SetupTwoFactor generates a new secret and returns it with the otpauth:// URI
+// to render as a QR code. 2FA is enforced only after EnableTwoFactor.
+func (h *UserHandler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		http.Error(w, "Failed generate secret", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.TwoFactor.SetPendingSecret(r.Context(), currentUserID(r), secret); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	user, err := h.Repo.GetUserByID(r.Context(), currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to fetch account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Scan the URI with an authenticator app, then confirm with a code",
+		"data": map[string]string{
+			"secret":      secret,
+			"otpauth_uri": totp.URI(h.TOTPIssuer, user.Email, secret),
+		},
+	})
+}
+
+// EnableTwoFactor confirms setup with a first code and returns the recovery
+// codes. They are only shown this once.
+func (h *UserHandler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req TwoFactorCodeRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	userID := currentUserID(r)
+	twoFactor, err := h.TwoFactor.GetTwoFactor(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to load two-factor settings", http.StatusInternalServerError)
+		return
+	}
+	if twoFactor.Enabled() {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	if twoFactor.Secret == "" {
+		http.Error(w, "Run two-factor setup first", http.StatusBadRequest)
+		return
+	}
+
+	step, ok := totp.Validate(twoFactor.Secret, strings.ReplaceAll(req.Code, " ", ""), time.Now(), totpSkew)
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.TwoFactor.EnableTwoFactor(r.Context(), userID, step)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Two-factor authentication enabled, store the recovery codes now: they will not be shown again",
+		"data": map[string][]string{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// DisableTwoFactor needs both the password and a current code, so neither a
+// stolen session nor a stolen phone alone can turn 2FA off.
+func (h *UserHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req DisableTwoFactorRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	user, err := h.Repo.GetUserByID(r.Context(), currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to fetch account", http.StatusInternalServerError)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		http.Error(w, "Invalid password or code", http.StatusBadRequest)
+		return
+	}
+
+	ok, err := h.verifySecondFactor(r.Context(), user.ID, req.Code)
+	if err != nil {
+		http.Error(w, "Failed verify code", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Invalid password or code", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.TwoFactor.DisableTwoFactor(r.Context(), user.ID); err != nil {
+		http.Error(w, "Failed disable two-factor", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current code.
+func (h *UserHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req TwoFactorCodeRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	userID := currentUserID(r)
+	ok, err := h.verifySecondFactor(r.Context(), userID, req.Code)
+	if err != nil {
+		http.Error(w, "Failed verify code", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.TwoFactor.RegenerateRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed regenerate recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Recovery codes regenerated, the old ones no longer work",
+		"data": map[string][]string{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// verifySecondFactor accepts a TOTP code (each step only once) or burns an
+// unused recovery code. It returns false when 2FA is not enabled.
+func (h *UserHandler) verifySecondFactor(ctx context.Context, userID, code string) (bool, error) {
+	twoFactor, err := h.TwoFactor.GetTwoFactor(ctx, userID)
+	if err != nil || !twoFactor.Enabled() {
+		return false, err
+	}
+
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(twoFactor.Secret, code, time.Now(), totpSkew)
+		if !ok {
+			return false, nil
+		}
+		return h.TwoFactor.UseTOTPStep(ctx, userID, step)
+	}
+
+	return h.TwoFactor.UseRecoveryCode(ctx, userID, code)
+}
+
+func writeTwoFactorError(w http.ResponseWriter, err error) {
+	if err.Error() == "two-factor already enabled" {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	http.Error(w, "Failed update two-factor settings", http.StatusInternalServerError)
+}
@@ -4,69 +4,219 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
-	"time"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 
+	"inventory-api/internal/auth"
+	"inventory-api/internal/logging"
+	"inventory-api/internal/mailer"
 	"inventory-api/internal/repository"
+	"inventory-api/internal/tokens"
 )
 
 type UserHandler struct {
-	Repo *repository.UserRepository
+	Repo        *repository.UserRepository
+	Orgs        *repository.OrganizationRepository
+	Invitations *repository.InvitationRepository
+	TwoFactor   *repository.TwoFactorRepository
+	Attempts    *repository.LoginAttemptRepository
+	JWT         *auth.TokenService
+	Mailer      mailer.Mailer
+	Tokens      *tokens.Signer
+	// BaseURL is the public URL used in links sent by email.
+	BaseURL string
+	// RequireVerifiedEmail makes LoginUser refuse accounts that have not verified their email.
+	RequireVerifiedEmail bool
+	// DefaultOrganization is the slug of the organization self-registered users join.
+	DefaultOrganization string
+	// RegistrationMode is RegistrationOpen, RegistrationInvite or RegistrationDisabled.
+	RegistrationMode string
+	// TOTPIssuer is the account label shown in authenticator apps.
+	TOTPIssuer string
+	// PasswordLoginDisabled leaves single sign-on as the only way to log in.
+	PasswordLoginDisabled bool
+}
+
+// Registration modes for RegistrationMode
+const (
+	RegistrationOpen     = "open"
+	RegistrationInvite   = "invite"
+	RegistrationDisabled = "disabled"
+)
+
+type RegisterRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=6"`
+	// InviteToken registers into the inviting organization with the invited role.
+	InviteToken string `json:"invite_token"`
 }
 
 func (h *UserHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
-	var user repository.User
+	if h.RegistrationMode == RegistrationDisabled {
+		http.Error(w, "Registration is disabled", http.StatusForbidden)
+		return
+	}
+
+	var req RegisterRequest
 
 	// 1. Decode JSON
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
 		return
 	}
 
 	// 2. Validasi Email dan Pass min 6 karakter
-	if err := validator.New().Struct(user); err != nil {
+	if err := validator.New().Struct(req); err != nil {
 		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
 		return
 	}
 
+	if h.RegistrationMode == RegistrationInvite && req.InviteToken == "" {
+		http.Error(w, "Registration requires an invitation", http.StatusForbidden)
+		return
+	}
+
 	// 3. HASH PASSWORD
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 
 	if err != nil {
 		http.Error(w, "Gagal memproses password", http.StatusInternalServerError)
 		return
 	}
 
-	user.Password = string(hashedPassword)
+	user := repository.User{
+		Email:    req.Email,
+		Password: string(hashedPassword),
+	}
 
-	// 4. Simpan ke Database
-	if err := h.Repo.CreateUser(r.Context(), &user); err != nil {
-		http.Error(w, "Failed register user", http.StatusInternalServerError)
-		return
+	// 4a. Lewat undangan: role & organisasi ditentukan admin yang mengundang
+	if req.InviteToken != "" {
+		if !h.acceptInvitation(w, r, req.InviteToken, &user) {
+			return
+		}
+	} else {
+		// 4b. Registrasi terbuka: selalu staff di organisasi default
+		user.Role = repository.RoleStaff
+
+		org, err := h.Orgs.GetOrganizationBySlug(r.Context(), h.DefaultOrganization)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Default organization unavailable", "slug", h.DefaultOrganization, "error", err)
+			http.Error(w, "Failed register user", http.StatusInternalServerError)
+			return
+		}
+
+		if err := h.Repo.CreateUser(r.Context(), &user, org.ID); err != nil {
+			http.Error(w, "Failed register user", http.StatusInternalServerError)
+			return
+		}
+
+		// 5. Kirim email verifikasi (gagal kirim tidak membatalkan registrasi)
+		if err := h.sendVerificationEmail(r.Context(), &user); err != nil {
+			logging.FromContext(r.Context()).Error("Failed send verification email", "user_id", user.ID, "error", err)
+		}
 	}
 
-	// 5. Response Sukses
-	w.WriteHeader(http.StatusCreated)
+	// 6. Response Sukses
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "User registered successfully",
 	})
 }
 
+// acceptInvitation creates the user from an invitation token, writing the error
+// response on failure.
+func (h *UserHandler) acceptInvitation(w http.ResponseWriter, r *http.Request, token string, user *repository.User) bool {
+	id, err := h.Tokens.Verify(token)
+	if err != nil {
+		http.Error(w, "Invalid or expired invitation", http.StatusBadRequest)
+		return false
+	}
+
+	if err := h.Invitations.AcceptInvitation(r.Context(), id, user); err != nil {
+		switch err.Error() {
+		case "invitation invalid or expired":
+			http.Error(w, "Invalid or expired invitation", http.StatusBadRequest)
+		case "invitation is for a different email":
+			http.Error(w, "Invitation is for a different email address", http.StatusBadRequest)
+		case "email already registered":
+			http.Error(w, "An account with this email already exists; sign in and accept the invitation at /me/invitations/accept", http.StatusConflict)
+		default:
+			http.Error(w, "Failed register user", http.StatusInternalServerError)
+		}
+		return false
+	}
+	return true
+}
+
+type AcceptInvitationRequest struct {
+	InviteToken string `json:"invite_token" validate:"required"`
+}
+
+// AcceptInvitation adds the signed-in user to the inviting organization, for
+// invitations sent to an email that already has an account.
+func (h *UserHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.Tokens.Verify(req.InviteToken)
+	if err != nil {
+		http.Error(w, "Invalid or expired invitation", http.StatusBadRequest)
+		return
+	}
+
+	orgID, err := h.Invitations.AcceptInvitationForUser(r.Context(), id, auth.UserID(r.Context()))
+	if err != nil {
+		switch err.Error() {
+		case "invitation invalid or expired":
+			http.Error(w, "Invalid or expired invitation", http.StatusBadRequest)
+		case "invitation is for a different email":
+			http.Error(w, "Invitation is for a different email address", http.StatusBadRequest)
+		case "already a member":
+			http.Error(w, "You are already a member of this organization", http.StatusConflict)
+		default:
+			http.Error(w, "Failed accept invitation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Invitation accepted",
+		"data":    map[string]string{"organization_id": orgID},
+	})
+}
+
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	// Organization is the slug to sign in to; empty picks the user's oldest membership.
+	Organization string `json:"organization"`
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token string `json:"token,omitempty"`
+	// MFARequired means the password was right but ChallengeToken and a TOTP or
+	// recovery code must be posted to /login/2fa to get the token.
+	MFARequired    bool   `json:"mfa_required,omitempty"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
 }
 
 func (h *UserHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
+	if h.PasswordLoginDisabled {
+		http.Error(w, "Password login is disabled, sign in with SSO", http.StatusForbidden)
+		return
+	}
+
 	var req LoginRequest
 
 	// 1. Decode & Validasi Input
@@ -75,43 +225,93 @@ func (h *UserHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 2. Cari User di Database by Email
+	// 2. Tolak dulu kalau email / IP ini sedang di-throttle atau terkunci.
+	// Percobaan ini langsung dihitung gagal, dan dikembalikan kalau password benar
+	ip := clientIP(r)
+	if !h.reserveLoginAttempt(w, r.Context(), req.Email, ip) {
+		return
+	}
+
+	// 3. Cari User di Database by Email, lalu cek password (Hash DB vs Input User)
 	user, err := h.Repo.GetUserByEmail(r.Context(), req.Email)
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	}
 	if err != nil {
 		// PENTING: Jangan bilang "Email tidak ditemukan" demi keamanan.
 		// Bilang saja "Invalid email or password" agar hacker bingung.
 		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
+	h.releaseLoginAttempt(r.Context(), req.Email, ip)
+
+	// 4. Email harus sudah diverifikasi (kalau diwajibkan)
+	if h.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		http.Error(w, "Email address not verified", http.StatusForbidden)
+		return
+	}
 
-	// 3. Cek Password (Bandingkan Hash DB vs Input User)
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	// 5. Token berlaku untuk satu organisasi, dengan role di organisasi itu
+	membership, ok := h.loadMembership(w, r, user.ID, req.Organization)
+	if !ok {
+		return
+	}
+
+	// 6. Kalau 2FA aktif, JWT baru diberikan di /login/2fa. Counter gagal
+	// login sengaja belum di-reset supaya kode OTP tidak bisa di-brute force
+	// dengan login ulang pakai password yang sudah diketahui.
+	twoFactor, err := h.TwoFactor.GetTwoFactor(r.Context(), user.ID)
 	if err != nil {
-		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+		http.Error(w, "Failed to load two-factor settings", http.StatusInternalServerError)
 		return
 	}
+	if twoFactor.Enabled() {
+		challenge, err := h.Repo.CreateUserToken(r.Context(), user.ID, repository.TokenMFAChallenge, mfaChallengeTTL)
+		if err != nil {
+			http.Error(w, "Failed create login challenge", http.StatusInternalServerError)
+			return
+		}
 
-	// 4. BIKIN TIKET (JWT) 🎫
-	// Menentukan masa berlaku token (misal 24 jam)
-	expirationTime := time.Now().Add(24 * time.Hour)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LoginResponse{
+			MFARequired:    true,
+			ChallengeToken: h.Tokens.Sign(challenge),
+		})
+		return
+	}
+
+	if _, err := h.Attempts.ClearFailure(r.Context(), repository.LoginScopeEmail, strings.ToLower(req.Email)); err != nil {
+		logging.FromContext(r.Context()).Error("Failed clear login failures", "error", err)
+	}
 
-	// Membuat Claims (Isi data di dalam token)
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"exp":     expirationTime.Unix(), // Expired kapan
+	h.writeLoginToken(w, user, membership)
+}
+
+// loadMembership resolves the organization to sign in to, writing the error response on failure.
+func (h *UserHandler) loadMembership(w http.ResponseWriter, r *http.Request, userID, orgSlug string) (repository.Membership, bool) {
+	membership, err := h.Orgs.GetMembership(r.Context(), userID, orgSlug)
+	if err != nil {
+		if err.Error() == "membership not found" {
+			http.Error(w, "Not a member of this organization", http.StatusForbidden)
+		} else {
+			http.Error(w, "Failed to load organization membership", http.StatusInternalServerError)
+		}
+		return membership, false
 	}
+	return membership, true
+}
 
-	// Tanda tangani token dengan JWT_SECRET dari .env
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+func (h *UserHandler) writeLoginToken(w http.ResponseWriter, user *repository.User, membership repository.Membership) {
+	// BIKIN TIKET (JWT) 🎫
+	// Berisi iss, aud, exp, nbf, iat & jti; masa berlaku diatur JWT_TTL
+	tokenString, err := h.JWT.Issue(user.ID, user.Email, membership.OrganizationID, membership.Role)
 
 	if err != nil {
 		http.Error(w, "Gagal generate token", http.StatusInternalServerError)
 		return
 	}
 
-	// 5. Kirim Token ke User
+	// Kirim Token ke User
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(LoginResponse{
 		Token: tokenString,
@@ -0,0 +1,105 @@
+// Package logging carries a request-scoped slog.Logger in the context and
+// writes one access log line per request.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type loggerKey struct{}
+
+type fieldsKey struct{}
+
+// requestFields collects attributes added by inner handlers (e.g. the user ID
+// set by the auth middleware) so the access log line, written by the outermost
+// middleware, includes them too.
+type requestFields struct {
+	mu   sync.Mutex
+	args []any
+}
+
+// WithLogger returns a context carrying l.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the request's logger, or slog.Default() outside a request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// With adds key/value pairs to the context logger and to the request's access log line.
+func With(ctx context.Context, args ...any) context.Context {
+	if f, ok := ctx.Value(fieldsKey{}).(*requestFields); ok {
+		f.mu.Lock()
+		f.args = append(f.args, args...)
+		f.mu.Unlock()
+	}
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
+
+// Middleware gives every request a logger tagged with its request ID and logs
+// method, route pattern, status and latency once it completes: 5xx as errors,
+// 4xx as warnings. It must run after chi's middleware.RequestID.
+func Middleware(base *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := middleware.GetReqID(r.Context())
+			if requestID != "" {
+				w.Header().Set(middleware.RequestIDHeader, requestID)
+			}
+
+			fields := &requestFields{}
+			ctx := context.WithValue(r.Context(), fieldsKey{}, fields)
+			ctx = WithLogger(ctx, base.With("request_id", requestID))
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			route := ""
+			if rctx := chi.RouteContext(ctx); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case status >= 500:
+				level = slog.LevelError
+			case status >= 400:
+				level = slog.LevelWarn
+			}
+
+			fields.mu.Lock()
+			args := append([]any{
+				"request_id", requestID,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", route,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+				"remote_addr", r.RemoteAddr,
+			}, fields.args...)
+			fields.mu.Unlock()
+
+			base.Log(ctx, level, "HTTP request", args...)
+		})
+	}
+}
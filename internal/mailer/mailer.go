@@ -0,0 +1,181 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"inventory-api/internal/config"
+	"inventory-api/internal/logging"
+)
+
+// smtpTimeout bounds a delivery when the caller's context has no deadline.
+const smtpTimeout = 30 * time.Second
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional email such as verification and password reset links.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer for cfg.Driver: "log" (default), "file", which writes
+// one .eml file per message into cfg.Dir, or "smtp".
+func New(cfg config.Mail) (Mailer, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return &LogMailer{From: cfg.From, LogBodies: cfg.LogBodies}, nil
+	case "file":
+		dir := cfg.Dir
+		if dir == "" {
+			dir = "mail"
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed create mail dir: %w", err)
+		}
+		return &FileMailer{From: cfg.From, Dir: dir}, nil
+	case "smtp":
+		return &SMTPMailer{
+			From:     cfg.From,
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// linkPattern matches the URLs in a body, which carry single-use tokens.
+var linkPattern = regexp.MustCompile(`https?://\S+`)
+
+// LogMailer writes messages to the structured log instead of sending them.
+// Links are redacted unless LogBodies is set, so tokens don't end up in logs.
+type LogMailer struct {
+	From      string
+	LogBodies bool
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	body := msg.Body
+	if !m.LogBodies {
+		body = linkPattern.ReplaceAllString(body, "[link redacted]")
+	}
+	logging.FromContext(ctx).Info("Mail sent", "from", m.From, "to", msg.To, "subject", msg.Subject, "body", body)
+	return nil
+}
+
+// FileMailer stores each message as an .eml file, handy for local development.
+type FileMailer struct {
+	From string
+	Dir  string
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFileName(msg.To))
+
+	if err := os.WriteFile(filepath.Join(m.Dir, name), formatMessage(m.From, msg), 0o644); err != nil {
+		return fmt.Errorf("failed write mail file: %w", err)
+	}
+	return nil
+}
+
+// SMTPMailer delivers through an SMTP server, upgrading to TLS with STARTTLS
+// when the server offers it. Credentials are only sent over TLS (or to localhost).
+type SMTPMailer struct {
+	From     string
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+
+	dialer := net.Dialer{Timeout: smtpTimeout}
+	c, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.Host, strconv.Itoa(m.Port)))
+	if err != nil {
+		return fmt.Errorf("failed connect smtp: %w", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	c.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(c, m.Host)
+	if err != nil {
+		c.Close()
+		return fmt.Errorf("failed smtp handshake: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+			return fmt.Errorf("failed starttls: %w", err)
+		}
+	}
+	if m.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return fmt.Errorf("failed smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("failed smtp sender: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("failed smtp recipient: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed smtp data: %w", err)
+	}
+	if _, err := w.Write(formatMessage(m.From, msg)); err != nil {
+		return fmt.Errorf("failed write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed send message: %w", err)
+	}
+	return client.Quit()
+}
+
+// formatMessage renders msg as a plain-text RFC 5322 message. Header values
+// have CR and LF stripped so they cannot inject extra headers.
+func formatMessage(from string, msg Message) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+	return []byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		header.Replace(from), header.Replace(msg.To), header.Replace(msg.Subject), time.Now().Format(time.RFC1123Z), msg.Body))
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, s)
+}
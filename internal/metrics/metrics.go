@@ -0,0 +1,221 @@
+// Package metrics exposes Prometheus metrics: HTTP requests labelled by chi
+// route pattern, pgxpool statistics and inventory gauges.
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"inventory-api/internal/logging"
+	"inventory-api/internal/repository"
+)
+
+const namespace = "inventory"
+
+// statsTimeout bounds the inventory query run on every scrape.
+const statsTimeout = 5 * time.Second
+
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// New creates a registry with the HTTP metrics plus the Go runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Register adds a collector such as NewPoolCollector or NewInventoryCollector.
+func (m *Metrics) Register(c prometheus.Collector) {
+	m.registry.MustRegister(c)
+}
+
+// Middleware records every request under its chi route pattern, so /products/{id}
+// is one series rather than one per product. Requests that match no route share
+// the "unmatched" label.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.requests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		m.duration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Handler serves the registry. With a non-empty token, scrapers must send it
+// as a bearer token since the gauges name every organization.
+func (m *Metrics) Handler(token string) http.Handler {
+	h := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return h
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	newConnsCount        *prometheus.Desc
+	maxLifetimeDestroy   *prometheus.Desc
+	maxIdleDestroy       *prometheus.Desc
+}
+
+// NewPoolCollector reports pool.Stat() on every scrape, labelled with name
+// ("primary", "replica" or "system").
+func NewPoolCollector(pool *pgxpool.Pool, name string) prometheus.Collector {
+	labels := prometheus.Labels{"pool": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", metric), help, nil, labels)
+	}
+
+	return &poolCollector{
+		pool:                 pool,
+		acquiredConns:        desc("acquired_connections", "Connections currently checked out of the pool."),
+		idleConns:            desc("idle_connections", "Idle connections in the pool."),
+		constructingConns:    desc("constructing_connections", "Connections being established."),
+		totalConns:           desc("total_connections", "All connections in the pool."),
+		maxConns:             desc("max_connections", "Maximum size of the pool."),
+		acquireCount:         desc("acquires_total", "Successful connection acquires."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Time spent waiting for a connection."),
+		canceledAcquireCount: desc("canceled_acquires_total", "Acquires canceled by their context."),
+		emptyAcquireCount:    desc("empty_acquires_total", "Acquires that had to wait because the pool was empty."),
+		newConnsCount:        desc("new_connections_total", "Connections opened."),
+		maxLifetimeDestroy:   desc("max_lifetime_destroys_total", "Connections closed for exceeding their max lifetime."),
+		maxIdleDestroy:       desc("max_idle_destroys_total", "Connections closed for exceeding their max idle time."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+
+	gauge := func(d *prometheus.Desc, v int32) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(v))
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+
+	gauge(c.acquiredConns, s.AcquiredConns())
+	gauge(c.idleConns, s.IdleConns())
+	gauge(c.constructingConns, s.ConstructingConns())
+	gauge(c.totalConns, s.TotalConns())
+	gauge(c.maxConns, s.MaxConns())
+	counter(c.acquireCount, float64(s.AcquireCount()))
+	counter(c.acquireDuration, s.AcquireDuration().Seconds())
+	counter(c.canceledAcquireCount, float64(s.CanceledAcquireCount()))
+	counter(c.emptyAcquireCount, float64(s.EmptyAcquireCount()))
+	counter(c.newConnsCount, float64(s.NewConnsCount()))
+	counter(c.maxLifetimeDestroy, float64(s.MaxLifetimeDestroyCount()))
+	counter(c.maxIdleDestroy, float64(s.MaxIdleDestroyCount()))
+}
+
+type inventoryCollector struct {
+	repo      *repository.ProductRepository
+	threshold int
+
+	products *prometheus.Desc
+	lowStock *prometheus.Desc
+}
+
+// NewInventoryCollector reports product totals and low-stock counts per
+// organization, querying the database on every scrape.
+func NewInventoryCollector(repo *repository.ProductRepository, lowStockThreshold int) prometheus.Collector {
+	return &inventoryCollector{
+		repo:      repo,
+		threshold: lowStockThreshold,
+		products: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "products"),
+			"Products in the catalog.", []string{"organization"}, nil),
+		lowStock: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "low_stock_products"),
+			"Products whose available quantity is at or below the low-stock threshold.", []string{"organization"}, nil),
+	}
+}
+
+func (c *inventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.products
+	ch <- c.lowStock
+}
+
+// Collect skips the gauges when the query fails, so a database outage shows up
+// as missing series instead of failing the whole scrape.
+func (c *inventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+	ctx = logging.With(ctx, "collector", "inventory")
+
+	stats, err := c.repo.GetInventoryStats(ctx, c.threshold)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed collect inventory metrics", "error", err)
+		return
+	}
+
+	for _, s := range stats {
+		ch <- prometheus.MustNewConstMetric(c.products, prometheus.GaugeValue, float64(s.Products), s.Organization)
+		ch <- prometheus.MustNewConstMetric(c.lowStock, prometheus.GaugeValue, float64(s.LowStock), s.Organization)
+	}
+}
@@ -1,26 +1,55 @@
 package middleware
 
 import (
-	"context"
 	"net/http"
-	"os"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
+	"inventory-api/internal/auth"
+	"inventory-api/internal/logging"
+	"inventory-api/internal/repository"
 )
 
+// Authenticator menerima Bearer JWT (user) atau API key (mesin: scanner, ERP).
+type Authenticator struct {
+	JWT     *auth.TokenService
+	APIKeys *repository.APIKeyRepository
+	// Members dicek tiap request, jadi user yang dinonaktifkan langsung ditolak
+	Members *repository.OrganizationRepository
+}
+
 // AuthMiddleware - Fungsi Satpam
-func AuthMiddleware(next http.Handler) http.Handler {
+func (a *Authenticator) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		// 1. Ambil Header Authorization
+		// 1. API key boleh lewat header X-API-Key atau "Authorization: ApiKey <key>"
+		if apiKey := apiKeyFromRequest(r); apiKey != "" {
+			key, err := a.APIKeys.AuthenticateAPIKey(r.Context(), apiKey)
+			if err != nil {
+				http.Error(w, "Invalid API Key", http.StatusUnauthorized)
+				return
+			}
+
+			// API key tidak membawa role, jadi tidak pernah bisa akses endpoint admin
+			ctx := auth.WithPrincipal(r.Context(), &auth.Principal{
+				UserID:         key.UserID,
+				Email:          key.OwnerEmail,
+				OrganizationID: key.OrganizationID,
+				AuthMethod:     auth.MethodAPIKey,
+				Scopes:         key.Scopes,
+			})
+			ctx = logging.With(ctx, "user_id", key.UserID, "organization_id", key.OrganizationID, "auth_method", auth.MethodAPIKey)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		// 2. Ambil Header Authorization
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			http.Error(w, "Missing Authorization Header", http.StatusUnauthorized)
 			return
 		}
 
-		// 2. Format harus "Bearer <token>"
+		// 3. Format harus "Bearer <token>"
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			http.Error(w, "Invalid Token Format", http.StatusUnauthorized)
@@ -29,28 +58,104 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 		tokenString := parts[1]
 
-		// 3. Parse & Validasi Token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Pastikan metode tanda tangannya benar (HMAC)
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, http.ErrAbortHandler
-			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		if err != nil || !token.Valid {
+		// 4. Parse & Validasi Token
+		// Signature (kid), iss, aud, exp, nbf & iat dicek semua, dengan leeway
+		claims, err := a.JWT.Parse(tokenString)
+		if err != nil {
 			http.Error(w, "Invalid or Expired Token", http.StatusUnauthorized)
 			return
 		}
 
-		// 4. (Opsional) Ambil data dari token (Claims) untuk dipakai di Handler
-		// Misal: Siapa yang login?
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Simpan user_id ke dalam Context agar bisa dibaca di Handler
-			ctx := context.WithValue(r.Context(), "user_id", claims["user_id"])
-			next.ServeHTTP(w, r.WithContext(ctx))
-		} else {
-			http.Error(w, "Invalid Claims", http.StatusUnauthorized)
+		// 5. Token yang masih valid tetap ditolak kalau user sudah dinonaktifkan
+		// atau dikeluarkan dari organisasi. Role diambil dari database, bukan
+		// dari token, supaya perubahan role langsung berlaku.
+		membership, err := a.Members.GetActiveMembership(r.Context(), claims.UserID, claims.OrgID)
+		if err != nil {
+			if err.Error() == "membership not found" {
+				http.Error(w, "Account deactivated", http.StatusUnauthorized)
+			} else {
+				http.Error(w, "Failed to check account", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		// 6. Simpan principal ke dalam Context, dibaca lewat auth.PrincipalFrom
+		// Organisasi dari token menentukan tenant untuk semua query repository
+		ctx := auth.WithPrincipal(r.Context(), &auth.Principal{
+			UserID:         claims.UserID,
+			Email:          claims.Email,
+			OrganizationID: claims.OrgID,
+			Roles:          []string{membership.Role},
+			AuthMethod:     auth.MethodJWT,
+		})
+		// Log request ini ikut mencatat siapa pemanggilnya
+		ctx = logging.With(ctx, "user_id", claims.UserID, "organization_id", claims.OrgID, "auth_method", auth.MethodJWT)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	if rest, ok := strings.CutPrefix(r.Header.Get("Authorization"), "ApiKey "); ok {
+		return strings.TrimSpace(rest)
+	}
+	return ""
+}
+
+// RequireRole hanya meloloskan request dari user dengan role tertentu.
+// Harus dipasang setelah AuthMiddleware.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := auth.PrincipalFrom(r.Context())
+			if !ok || !p.HasRole(role) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope membatasi API key ke "<resource>:read" untuk GET/HEAD dan
+// "<resource>:write" untuk method lain. Login user (JWT) tidak dibatasi scope.
+func RequireScope(resource string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := auth.PrincipalFrom(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if p.AuthMethod != auth.MethodAPIKey {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scope := resource + ":write"
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				scope = resource + ":read"
+			}
+
+			if !p.HasScope(scope) {
+				http.Error(w, "API key missing scope "+scope, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireUser menolak API key, untuk endpoint yang hanya boleh dipakai user login (JWT).
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := auth.PrincipalFrom(r.Context()); !ok || p.AuthMethod != auth.MethodJWT {
+			http.Error(w, "This endpoint requires a user login", http.StatusForbidden)
+			return
 		}
+		next.ServeHTTP(w, r)
 	})
 }
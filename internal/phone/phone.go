@@ -0,0 +1,40 @@
+package phone
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// e164 is a plus sign followed by 7 to 15 digits, the first one non-zero.
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+var separators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// Normalize converts a phone number to E.164 form, e.g. "0812-3456-789" with
+// default country code "62" becomes "+628123456789".
+//
+// An international "00" prefix is replaced by "+". A national number with a
+// leading 0 needs defaultCountryCode; without a "+", "00" or "0" prefix the
+// digits are assumed to already start with the country code.
+func Normalize(raw, defaultCountryCode string) (string, error) {
+	s := separators.Replace(strings.TrimSpace(raw))
+
+	switch {
+	case strings.HasPrefix(s, "+"):
+	case strings.HasPrefix(s, "00"):
+		s = "+" + s[2:]
+	case strings.HasPrefix(s, "0"):
+		if defaultCountryCode == "" {
+			return "", fmt.Errorf("phone number %q has no country code", raw)
+		}
+		s = "+" + strings.TrimPrefix(defaultCountryCode, "+") + s[1:]
+	default:
+		s = "+" + s
+	}
+
+	if !e164.MatchString(s) {
+		return "", fmt.Errorf("phone number %q is not a valid E.164 number", raw)
+	}
+	return s, nil
+}
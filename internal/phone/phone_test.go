@@ -0,0 +1,38 @@
+package phone
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		countryCode string
+		want        string
+		wantErr     bool
+	}{
+		{"national with separators", "0812-3456-789", "62", "+628123456789", false},
+		{"national with plus country code", "0812 3456 789", "+62", "+628123456789", false},
+		{"already E.164", "+62 812 3456 789", "", "+628123456789", false},
+		{"international 00 prefix", "0044 (20) 7946.0958", "62", "+442079460958", false},
+		{"digits start with country code", "628123456789", "", "+628123456789", false},
+		{"surrounding whitespace", "  +14155552671 ", "", "+14155552671", false},
+		{"national without default country code", "08123456789", "", "", true},
+		{"too short", "+12345", "", "", true},
+		{"too long", "+1234567890123456", "", "", true},
+		{"leading zero country code", "+0123456789", "", "", true},
+		{"letters", "+62 812 CALL ME", "", "", true},
+		{"empty", "", "62", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw, tt.countryCode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize(%q, %q) error = %v, wantErr %v", tt.raw, tt.countryCode, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q, %q) = %q, want %q", tt.raw, tt.countryCode, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"inventory-api/internal/auth"
+)
+
+// actorID returns the authenticated user's ID for created_by/updated_by
+// columns, or nil (NULL) when the request is anonymous.
+func actorID(ctx context.Context) *string {
+	if id := auth.UserID(ctx); id != "" {
+		return &id
+	}
+	return nil
+}
+
+// tenantID returns the caller's organization for scoping queries. Anonymous
+// requests get nil (NULL), which matches no rows and fails NOT NULL on insert;
+// the row-level security policies fail closed the same way, so a missing
+// tenant can never read or write another tenant's data.
+func tenantID(ctx context.Context) *string {
+	if id := auth.OrganizationID(ctx); id != "" {
+		return &id
+	}
+	return nil
+}
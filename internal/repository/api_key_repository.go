@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Scopes grant read (GET) or write (everything else) access to one resource.
+var APIKeyScopes = []string{
+	"products:read", "products:write",
+	"categories:read", "categories:write",
+	"customers:read", "customers:write",
+	"reservations:read", "reservations:write",
+	"stock-counts:read", "stock-counts:write",
+	"returns:read", "returns:write",
+}
+
+// apiKeyPrefix marks our keys so they are easy to spot in logs and secret scanners.
+const apiKeyPrefix = "inv"
+
+type APIKey struct {
+	ID             string     `json:"id"`
+	OrganizationID string     `json:"organization_id"`
+	UserID         string     `json:"user_id"`
+	Name           string     `json:"name"`
+	Prefix         string     `json:"prefix"`
+	Scopes         []string   `json:"scopes"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+
+	// Key is the plaintext key, only set in the response to CreateAPIKey.
+	Key string `json:"key,omitempty"`
+	// OwnerEmail is filled in by AuthenticateAPIKey.
+	OwnerEmail string `json:"-"`
+}
+
+type APIKeyRepository struct {
+	DB *pgxpool.Pool
+	// System looks keys up before the organization is known.
+	System *pgxpool.Pool
+}
+
+const apiKeyColumns = "id, organization_id, user_id, name, prefix, scopes, created_at, last_used_at, expires_at, revoked_at"
+
+func scanAPIKey(row pgx.Row, k *APIKey) error {
+	return row.Scan(&k.ID, &k.OrganizationID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.LastUsedAt, &k.ExpiresAt, &k.RevokedAt)
+}
+
+// CreateAPIKey generates a key of the form "inv_<prefix>_<secret>", stores only
+// its SHA-256 hash and sets k.Key to the plaintext for the caller to hand out once.
+// The key belongs to the caller's organization and only ever acts within it.
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, k *APIKey, ttl time.Duration) error {
+	prefixBytes := make([]byte, 6)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return fmt.Errorf("failed generate key: %w", err)
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return fmt.Errorf("failed generate key: %w", err)
+	}
+
+	k.Prefix = hex.EncodeToString(prefixBytes)
+	k.Key = apiKeyPrefix + "_" + k.Prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+
+	var expiresIn *float64
+	if ttl > 0 {
+		seconds := ttl.Seconds()
+		expiresIn = &seconds
+	}
+
+	query := `
+		INSERT INTO api_keys (organization_id, user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP + make_interval(secs => $7))
+		RETURNING ` + apiKeyColumns
+	key := k.Key
+	err := scanAPIKey(conn(ctx, r.DB).QueryRow(ctx, query, tenantID(ctx), k.UserID, k.Name, k.Prefix, hashAPIKey(k.Key), k.Scopes, expiresIn), k)
+	if err != nil {
+		return fmt.Errorf("failed insert api key: %w", err)
+	}
+	k.Key = key
+
+	return nil
+}
+
+// AuthenticateAPIKey returns the active key matching the plaintext key and
+// records its use. last_used_at is written at most once a minute per key.
+// Keys stop working once their owner leaves or is deactivated in the key's organization.
+func (r *APIKeyRepository) AuthenticateAPIKey(ctx context.Context, key string) (*APIKey, error) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return nil, fmt.Errorf("invalid api key")
+	}
+
+	var k APIKey
+	var keyHash string
+	query := `
+		SELECT k.id, k.organization_id, k.user_id, k.name, k.prefix, k.scopes, k.created_at, k.last_used_at, k.expires_at, k.revoked_at,
+			k.key_hash, u.email
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		JOIN organization_members m ON m.organization_id = k.organization_id AND m.user_id = k.user_id
+			AND m.deactivated_at IS NULL
+		WHERE k.prefix = $1 AND k.revoked_at IS NULL
+		AND (k.expires_at IS NULL OR k.expires_at > CURRENT_TIMESTAMP)
+	`
+	db := systemDB(r.DB, r.System)
+	err := db.QueryRow(ctx, query, parts[1]).Scan(&k.ID, &k.OrganizationID, &k.UserID, &k.Name, &k.Prefix,
+		&k.Scopes, &k.CreatedAt, &k.LastUsedAt, &k.ExpiresAt, &k.RevokedAt, &keyHash, &k.OwnerEmail)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("invalid api key")
+		}
+		return nil, fmt.Errorf("failed query api key: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(keyHash), []byte(hashAPIKey(key))) != 1 {
+		return nil, fmt.Errorf("invalid api key")
+	}
+
+	query = `
+		UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+	`
+	if _, err := db.Exec(ctx, query, k.ID); err != nil {
+		return nil, fmt.Errorf("failed update last used: %w", err)
+	}
+
+	return &k, nil
+}
+
+// GetAPIKeys lists the organization's keys owned by userID, or all of them when
+// userID is empty.
+func (r *APIKeyRepository) GetAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	keys := []APIKey{}
+
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE ($1 = '' OR user_id::text = $1) AND organization_id = $2 ORDER BY created_at DESC"
+	rows, err := conn(ctx, r.DB).Query(ctx, query, userID, tenantID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k APIKey
+		if err := scanAPIKey(rows, &k); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey revokes a key. A non-empty userID restricts it to keys owned by that user.
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id, userID string) error {
+	query := `
+		UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($2 = '' OR user_id::text = $2) AND revoked_at IS NULL AND organization_id = $3
+	`
+
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, query, id, userID, tenantID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed revoke: %w", err)
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("api key not found")
+	}
+
+	return nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -10,16 +10,21 @@ import (
 type Category struct {
 	ID   string `json:"id"`
 	Name string `json:"name" validate:"required"`
+
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
 }
 
 type CategoryRepository struct {
 	DB *pgxpool.Pool
+	// Replica, when set, serves GetAllCategories.
+	Replica *pgxpool.Pool
 }
 
 func (r *CategoryRepository) CreateCategory(ctx context.Context, c *Category) error {
-	query := `INSERT INTO categories (name) VALUES ($1) RETURNING id`
+	query := `INSERT INTO categories (organization_id, name, created_by, updated_by) VALUES ($1, $2, $3, $3) RETURNING id, COALESCE(created_by::text, '')`
 
-	err := r.DB.QueryRow(ctx, query, c.Name).Scan(&c.ID)
+	err := conn(ctx, r.DB).QueryRow(ctx, query, tenantID(ctx), c.Name, actorID(ctx)).Scan(&c.ID, &c.CreatedBy)
 	if err != nil {
 		return fmt.Errorf("failed insert category: %w", err)
 	}
@@ -29,8 +34,8 @@ func (r *CategoryRepository) CreateCategory(ctx context.Context, c *Category) er
 func (r *CategoryRepository) GetAllCategories(ctx context.Context) ([]Category, error) {
 	categories := []Category{}
 
-	query := `SELECT id, name FROM categories`
-	rows, err := r.DB.Query(ctx, query)
+	query := `SELECT id, name, COALESCE(created_by::text, ''), COALESCE(updated_by::text, '') FROM categories WHERE organization_id = $1`
+	rows, err := readDB(ctx, r.DB, r.Replica).Query(ctx, query, tenantID(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed query: %w", err)
 	}
@@ -38,7 +43,7 @@ func (r *CategoryRepository) GetAllCategories(ctx context.Context) ([]Category,
 
 	for rows.Next() {
 		var c Category
-		if err := rows.Scan(&c.ID, &c.Name); err != nil {
+		if err := rows.Scan(&c.ID, &c.Name, &c.CreatedBy, &c.UpdatedBy); err != nil {
 			return nil, err
 		}
 		categories = append(categories, c)
@@ -47,9 +52,9 @@ func (r *CategoryRepository) GetAllCategories(ctx context.Context) ([]Category,
 }
 
 func (r *CategoryRepository) DeleteCategory(ctx context.Context, id string) error {
-	query := "DELETE FROM categories WHERE id=$1"
+	query := "DELETE FROM categories WHERE id=$1 AND organization_id=$2"
 
-	commandTag, err := r.DB.Exec(ctx, query, id)
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, query, id, tenantID(ctx))
 	if err != nil {
 		return fmt.Errorf("failed delete: %w", err)
 	}
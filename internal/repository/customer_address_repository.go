@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CustomerAddress struct {
+	ID         string `json:"id"`
+	CustomerID string `json:"customer_id"`
+	Type       string `json:"type" validate:"required,oneof=billing shipping"`
+	Line1      string `json:"line1" validate:"required,max=255"`
+	Line2      string `json:"line2" validate:"max=255"`
+	City       string `json:"city" validate:"required,max=100"`
+	Region     string `json:"region" validate:"max=100"`
+	PostalCode string `json:"postal_code" validate:"max=20"`
+	Country    string `json:"country" validate:"required,iso3166_1_alpha2"`
+	IsDefault  bool   `json:"is_default"`
+}
+
+type CustomerAddressRepository struct {
+	DB *pgxpool.Pool
+}
+
+const customerAddressColumns = "id, customer_id, type, line1, line2, city, region, postal_code, country, is_default"
+
+func scanCustomerAddress(row pgx.Row, a *CustomerAddress) error {
+	return row.Scan(&a.ID, &a.CustomerID, &a.Type, &a.Line1, &a.Line2, &a.City, &a.Region, &a.PostalCode, &a.Country, &a.IsDefault)
+}
+
+func (r *CustomerAddressRepository) GetAddressesByCustomer(ctx context.Context, customerID string) ([]CustomerAddress, error) {
+	addresses := []CustomerAddress{}
+
+	query := "SELECT " + customerAddressColumns + " FROM customer_addresses WHERE customer_id = $1 AND organization_id = $2 ORDER BY type, is_default DESC, created_at"
+	rows, err := conn(ctx, r.DB).Query(ctx, query, customerID, tenantID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a CustomerAddress
+		if err := scanCustomerAddress(rows, &a); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+func (r *CustomerAddressRepository) CreateAddress(ctx context.Context, a *CustomerAddress) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1 AND organization_id = $2)"
+	if err := tx.QueryRow(ctx, query, a.CustomerID, tenantID(ctx)).Scan(&exists); err != nil {
+		return fmt.Errorf("failed check customer: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("customer not found")
+	}
+
+	if a.IsDefault {
+		if err := clearDefaultAddress(ctx, tx, a.CustomerID, a.Type); err != nil {
+			return err
+		}
+	}
+
+	query = `
+		INSERT INTO customer_addresses (organization_id, customer_id, type, line1, line2, city, region, postal_code, country, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+	err = tx.QueryRow(ctx, query, tenantID(ctx), a.CustomerID, a.Type, a.Line1, a.Line2, a.City, a.Region, a.PostalCode, a.Country, a.IsDefault).Scan(&a.ID)
+	if err != nil {
+		return fmt.Errorf("failed insert address: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *CustomerAddressRepository) UpdateAddress(ctx context.Context, a *CustomerAddress) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if a.IsDefault {
+		if err := clearDefaultAddress(ctx, tx, a.CustomerID, a.Type); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE customer_addresses
+		SET type=$1, line1=$2, line2=$3, city=$4, region=$5, postal_code=$6, country=$7, is_default=$8
+		WHERE id=$9 AND customer_id=$10 AND organization_id=$11
+	`
+	commandTag, err := tx.Exec(ctx, query, a.Type, a.Line1, a.Line2, a.City, a.Region, a.PostalCode, a.Country, a.IsDefault, a.ID, a.CustomerID, tenantID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed Update: %w", err)
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("address not found")
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *CustomerAddressRepository) DeleteAddress(ctx context.Context, customerID, id string) error {
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, "DELETE FROM customer_addresses WHERE id=$1 AND customer_id=$2 AND organization_id=$3", id, customerID, tenantID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed delete: %w", err)
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("address not found")
+	}
+
+	return nil
+}
+
+func (r *CustomerAddressRepository) GetAddressByID(ctx context.Context, customerID, id string) (CustomerAddress, error) {
+	var a CustomerAddress
+
+	query := "SELECT " + customerAddressColumns + " FROM customer_addresses WHERE id = $1 AND customer_id = $2 AND organization_id = $3"
+	if err := scanCustomerAddress(conn(ctx, r.DB).QueryRow(ctx, query, id, customerID, tenantID(ctx)), &a); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return a, fmt.Errorf("address not found")
+		}
+		return a, fmt.Errorf("failed query address: %w", err)
+	}
+
+	return a, nil
+}
+
+// clearDefaultAddress keeps at most one default address per customer and type.
+func clearDefaultAddress(ctx context.Context, tx pgx.Tx, customerID, addressType string) error {
+	query := "UPDATE customer_addresses SET is_default = FALSE WHERE customer_id = $1 AND type = $2 AND is_default AND organization_id = $3"
+	if _, err := tx.Exec(ctx, query, customerID, addressType, tenantID(ctx)); err != nil {
+		return fmt.Errorf("failed clear default address: %w", err)
+	}
+	return nil
+}
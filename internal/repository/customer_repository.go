@@ -2,8 +2,11 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -11,45 +14,186 @@ type Customer struct {
 	ID    string `json:"id"`
 	Name  string `json:"name" validate:"required"`
 	Email string `json:"email" validate:"required,email"`
-	Phone string `json:"phone" validate:"required"`
+	Phone string `json:"phone" validate:"required,e164"`
+	TaxID string `json:"tax_id" validate:"max=50"`
+	Notes string `json:"notes"`
+
+	Addresses []CustomerAddress `json:"addresses,omitempty"`
+
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
 }
 
 type CustomerRepository struct {
 	DB *pgxpool.Pool
+	// Replica, when set, serves the paginated customer list.
+	Replica *pgxpool.Pool
+}
+
+// CustomerFilter narrows GetAllCustomers. Query matches name, email or phone;
+// the other fields match their own column. All matching is case-insensitive substring.
+type CustomerFilter struct {
+	Query   string
+	Name    string
+	Email   string
+	Phone   string
+	Page    int
+	PerPage int
+}
+
+// DuplicateCustomerError is returned when a customer with the same email exists.
+type DuplicateCustomerError struct {
+	ExistingID string
+}
+
+func (e *DuplicateCustomerError) Error() string {
+	return "customer already exists"
 }
 
 func (r *CustomerRepository) CreateCustomer(ctx context.Context, c *Customer) error {
 	query := `
-		INSERT INTO customers (name, email, phone)
-		VALUES ($1, $2, $3)
-		RETURNING id
+		INSERT INTO customers (organization_id, name, email, phone, tax_id, notes, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING id, COALESCE(created_by::text, '')
 	`
 
-	err := r.DB.QueryRow(ctx, query, c.Name, c.Email, c.Phone).Scan(&c.ID)
+	err := conn(ctx, r.DB).QueryRow(ctx, query, tenantID(ctx), c.Name, c.Email, c.Phone, c.TaxID, c.Notes, actorID(ctx)).Scan(&c.ID, &c.CreatedBy)
 
 	if err != nil {
+		if dupErr := r.duplicateError(ctx, err, c.Email); dupErr != nil {
+			return dupErr
+		}
 		return fmt.Errorf("failed insert database: %w", err)
 	}
 
 	return nil
 }
 
-func (r *CustomerRepository) GetAllCustomers(ctx context.Context) ([]Customer, error) {
+func (r *CustomerRepository) UpdateCustomer(ctx context.Context, id string, c *Customer) error {
+	query := "UPDATE customers SET name=$1, email=$2, phone=$3, tax_id=$4, notes=$5, updated_by=$6 WHERE id=$7 AND organization_id=$8"
+
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, query, c.Name, c.Email, c.Phone, c.TaxID, c.Notes, actorID(ctx), id, tenantID(ctx))
+	if err != nil {
+		if dupErr := r.duplicateError(ctx, err, c.Email); dupErr != nil {
+			return dupErr
+		}
+		return fmt.Errorf("failed Update: %w", err)
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("customer not found")
+	}
+
+	c.ID = id
+	return nil
+}
+
+// duplicateError turns a unique violation on email into a DuplicateCustomerError
+// carrying the ID of the existing customer in the same organization. It returns nil for any other error.
+func (r *CustomerRepository) duplicateError(ctx context.Context, err error, email string) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return nil
+	}
+
+	dup := &DuplicateCustomerError{}
+	if lookupErr := conn(ctx, r.DB).QueryRow(ctx, "SELECT id FROM customers WHERE email = $1 AND organization_id = $2", email, tenantID(ctx)).Scan(&dup.ExistingID); lookupErr != nil {
+		return fmt.Errorf("failed lookup duplicate customer: %w", lookupErr)
+	}
+	return dup
+}
+
+const customerColumns = "id, name, email, phone, tax_id, notes, COALESCE(created_by::text, ''), COALESCE(updated_by::text, '')"
+
+// customerFilterWhere is shared by the page and count queries of
+// GetAllCustomers. The filter values are passed through escapeLike.
+const customerFilterWhere = `
+	WHERE ($1 = '' OR name ILIKE '%' || $1 || '%' ESCAPE '\' OR email ILIKE '%' || $1 || '%' ESCAPE '\' OR phone ILIKE '%' || $1 || '%' ESCAPE '\')
+	AND ($2 = '' OR name ILIKE '%' || $2 || '%' ESCAPE '\')
+	AND ($3 = '' OR email ILIKE '%' || $3 || '%' ESCAPE '\')
+	AND ($4 = '' OR phone ILIKE '%' || $4 || '%' ESCAPE '\')
+	AND organization_id = $5
+`
+
+// GetAllCustomers returns one page of customers matching f and the total number of matches.
+func (r *CustomerRepository) GetAllCustomers(ctx context.Context, f CustomerFilter) ([]Customer, int, error) {
 	customers := []Customer{}
+	args := []any{escapeLike(f.Query), escapeLike(f.Name), escapeLike(f.Email), escapeLike(f.Phone), tenantID(ctx)}
 
-	query := "SELECT id, name, email, phone FROM customers"
-	rows, err := r.DB.Query(ctx, query)
+	var total int
+	if err := readDB(ctx, r.DB, r.Replica).QueryRow(ctx, "SELECT COUNT(*) FROM customers"+customerFilterWhere, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed count: %w", err)
+	}
+
+	query := "SELECT " + customerColumns + " FROM customers" + customerFilterWhere + "ORDER BY name, id LIMIT $6 OFFSET $7"
+	rows, err := readDB(ctx, r.DB, r.Replica).Query(ctx, query, append(args, f.PerPage, (f.Page-1)*f.PerPage)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed Query: %w", err)
+		return nil, 0, fmt.Errorf("failed Query: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var c Customer
-		if err := rows.Scan(&c.ID, &c.Name, &c.Email, &c.Phone); err != nil {
-			return nil, fmt.Errorf("failed to scan: %w", err)
+		if err := rows.Scan(&c.ID, &c.Name, &c.Email, &c.Phone, &c.TaxID, &c.Notes, &c.CreatedBy, &c.UpdatedBy); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan: %w", err)
 		}
 		customers = append(customers, c)
 	}
-	return customers, nil
+	return customers, total, rows.Err()
+}
+
+func (r *CustomerRepository) GetCustomerByID(ctx context.Context, id string) (Customer, error) {
+	var c Customer
+
+	err := conn(ctx, r.DB).QueryRow(ctx, "SELECT "+customerColumns+" FROM customers WHERE id = $1 AND organization_id = $2", id, tenantID(ctx)).Scan(&c.ID, &c.Name, &c.Email, &c.Phone, &c.TaxID, &c.Notes, &c.CreatedBy, &c.UpdatedBy)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c, fmt.Errorf("customer not found")
+		}
+		return c, fmt.Errorf("failed query customer: %w", err)
+	}
+
+	return c, nil
+}
+
+// MergeCustomers moves every record referencing sourceID to targetID and then
+// deletes the source customer. The target keeps its own contact details.
+func (r *CustomerRepository) MergeCustomers(ctx context.Context, targetID, sourceID string) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock both rows in a stable order to avoid deadlocks with a concurrent reverse merge
+	var locked int
+	query := "SELECT COUNT(*) FROM (SELECT id FROM customers WHERE id IN ($1, $2) AND organization_id = $3 ORDER BY id FOR UPDATE) c"
+	if err := tx.QueryRow(ctx, query, targetID, sourceID, tenantID(ctx)).Scan(&locked); err != nil {
+		return fmt.Errorf("failed lock customers: %w", err)
+	}
+	if locked != 2 {
+		return fmt.Errorf("customer not found")
+	}
+
+	// Moved addresses must not compete with the target's own defaults
+	_, err = tx.Exec(ctx, "UPDATE customer_addresses SET customer_id = $1, is_default = FALSE WHERE customer_id = $2", targetID, sourceID)
+	if err != nil {
+		return fmt.Errorf("failed reassign customer_addresses: %w", err)
+	}
+
+	for _, table := range []string{"stock_reservations", "returns"} {
+		if _, err := tx.Exec(ctx, "UPDATE "+table+" SET customer_id = $1 WHERE customer_id = $2", targetID, sourceID); err != nil {
+			return fmt.Errorf("failed reassign %s: %w", table, err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM customers WHERE id = $1", sourceID); err != nil {
+		return fmt.Errorf("failed delete source customer: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE customers SET updated_by = $1 WHERE id = $2", actorID(ctx), targetID); err != nil {
+		return fmt.Errorf("failed update target customer: %w", err)
+	}
+
+	return tx.Commit(ctx)
 }
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HealthRepository struct {
+	DB *pgxpool.Pool
+	// Replica is nil when no read replica is configured.
+	Replica *pgxpool.Pool
+}
+
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	return r.DB.Ping(ctx)
+}
+
+func (r *HealthRepository) HasReplica() bool {
+	return r.Replica != nil
+}
+
+func (r *HealthRepository) PingReplica(ctx context.Context) error {
+	return r.Replica.Ping(ctx)
+}
+
+// GetSchemaVersion reads the version golang-migrate recorded in
+// schema_migrations. Dirty means a migration failed halfway.
+func (r *HealthRepository) GetSchemaVersion(ctx context.Context) (version uint, dirty bool, err error) {
+	var v int64
+	err = conn(ctx, r.DB).QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&v, &dirty)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, fmt.Errorf("no migrations applied")
+		}
+		return 0, false, fmt.Errorf("failed query schema version: %w", err)
+	}
+	return uint(v), dirty, nil
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"inventory-api/internal/logging"
+)
+
+// ExternalIdentity is an account at an OpenID Connect provider.
+type ExternalIdentity struct {
+	Issuer  string
+	Subject string
+	Email   string
+}
+
+// LoginState is a pending single sign-on login.
+type LoginState struct {
+	ID           string
+	Nonce        string
+	CodeVerifier string
+}
+
+type IdentityRepository struct {
+	DB *pgxpool.Pool
+}
+
+// CreateLoginState stores a new pending login. Logins that were started but
+// never completed are pruned here once expired, so anonymous requests to the
+// login endpoint cannot grow the table without bound.
+func (r *IdentityRepository) CreateLoginState(ctx context.Context, s *LoginState, ttl time.Duration) error {
+	// Pruning is housekeeping; a failure here shouldn't block the login itself
+	if _, err := conn(ctx, r.DB).Exec(ctx, "DELETE FROM oidc_login_states WHERE expires_at <= CURRENT_TIMESTAMP"); err != nil {
+		logging.FromContext(ctx).Warn("Failed prune login states", "error", err)
+	}
+
+	query := `
+		INSERT INTO oidc_login_states (nonce, code_verifier, expires_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(secs => $3))
+		RETURNING id
+	`
+	if err := conn(ctx, r.DB).QueryRow(ctx, query, s.Nonce, s.CodeVerifier, ttl.Seconds()).Scan(&s.ID); err != nil {
+		return fmt.Errorf("failed insert login state: %w", err)
+	}
+	return nil
+}
+
+// ConsumeLoginState deletes an unexpired login state and returns it, so each
+// state can complete at most one login.
+func (r *IdentityRepository) ConsumeLoginState(ctx context.Context, id string) (LoginState, error) {
+	s := LoginState{ID: id}
+
+	query := `
+		DELETE FROM oidc_login_states
+		WHERE id = $1 AND expires_at > CURRENT_TIMESTAMP
+		RETURNING nonce, code_verifier
+	`
+	if err := conn(ctx, r.DB).QueryRow(ctx, query, id).Scan(&s.Nonce, &s.CodeVerifier); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return s, fmt.Errorf("login state invalid or expired")
+		}
+		return s, fmt.Errorf("failed consume login state: %w", err)
+	}
+	return s, nil
+}
+
+// ProvisionUser returns the user behind an external identity, creating it on
+// first login. An identity seen for the first time is linked to the local user
+// with the same email, so existing accounts move over to single sign-on.
+// The membership in orgSlug is created or updated to role: the provider is
+// the source of truth for roles, except that the last active admin is not
+// demoted. A deactivated membership stays deactivated.
+func (r *IdentityRepository) ProvisionUser(ctx context.Context, id ExternalIdentity, orgSlug, role string) (*User, error) {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var orgID string
+	if err := tx.QueryRow(ctx, "SELECT id FROM organizations WHERE slug = $1", orgSlug).Scan(&orgID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("organization not found")
+		}
+		return nil, fmt.Errorf("failed query organization: %w", err)
+	}
+
+	var u User
+	query := `
+		UPDATE user_identities SET email = $3, last_login_at = CURRENT_TIMESTAMP
+		WHERE issuer = $1 AND subject = $2
+		RETURNING user_id
+	`
+	err = tx.QueryRow(ctx, query, id.Issuer, id.Subject, id.Email).Scan(&u.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = linkIdentity(ctx, tx, id, &u)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockOrganization(ctx, tx, &orgID); err != nil {
+		return nil, err
+	}
+
+	var previousRole string
+	query = "SELECT role FROM organization_members WHERE organization_id = $1 AND user_id = $2"
+	if err := tx.QueryRow(ctx, query, orgID, u.ID).Scan(&previousRole); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed query membership: %w", err)
+	}
+
+	query = `
+		INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id, user_id) DO UPDATE SET role = EXCLUDED.role
+	`
+	if _, err := tx.Exec(ctx, query, orgID, u.ID, role); err != nil {
+		return nil, fmt.Errorf("failed upsert membership: %w", err)
+	}
+
+	if previousRole == RoleAdmin && role != RoleAdmin {
+		if err := requireActiveAdmin(ctx, tx, &orgID); err != nil {
+			return nil, err
+		}
+	}
+
+	query = `SELECT email, email_verified_at FROM users WHERE id = $1`
+	if err := tx.QueryRow(ctx, query, u.ID).Scan(&u.Email, &u.EmailVerifiedAt); err != nil {
+		return nil, fmt.Errorf("failed query user: %w", err)
+	}
+	u.Role = role
+
+	return &u, tx.Commit(ctx)
+}
+
+// linkIdentity attaches a new identity to the user with its email, creating a
+// passwordless user when there is none. The provider has verified the email,
+// so the local account is marked verified as well.
+func linkIdentity(ctx context.Context, tx pgx.Tx, id ExternalIdentity, u *User) error {
+	query := `
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+		WHERE lower(email) = lower($1)
+		RETURNING id
+	`
+	err := tx.QueryRow(ctx, query, id.Email).Scan(&u.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// An empty hash never matches in bcrypt, so the user can only sign in through the provider
+		query = `INSERT INTO users (email, password, email_verified_at) VALUES ($1, '', CURRENT_TIMESTAMP) RETURNING id`
+		err = tx.QueryRow(ctx, query, id.Email).Scan(&u.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed provision user: %w", err)
+	}
+
+	query = `INSERT INTO user_identities (issuer, subject, user_id, email) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.Exec(ctx, query, id.Issuer, id.Subject, u.ID, id.Email); err != nil {
+		return fmt.Errorf("failed insert identity: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Invitation lets someone register into an organization with a preset role.
+type Invitation struct {
+	ID             string     `json:"id"`
+	OrganizationID string     `json:"organization_id"`
+	Email          string     `json:"email" validate:"required,email"`
+	Role           string     `json:"role" validate:"required,oneof=admin staff"`
+	InvitedBy      string     `json:"invited_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+}
+
+type InvitationRepository struct {
+	DB *pgxpool.Pool
+	// System reads the invitation on acceptance, before the user has an organization.
+	System *pgxpool.Pool
+}
+
+const invitationColumns = "id, organization_id, email, role, COALESCE(invited_by::text, ''), created_at, expires_at, accepted_at, revoked_at"
+
+func scanInvitation(row pgx.Row, inv *Invitation) error {
+	return row.Scan(&inv.ID, &inv.OrganizationID, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.CreatedAt, &inv.ExpiresAt, &inv.AcceptedAt, &inv.RevokedAt)
+}
+
+// CreateInvitation invites inv.Email into the caller's organization.
+func (r *InvitationRepository) CreateInvitation(ctx context.Context, inv *Invitation, ttl time.Duration) error {
+	query := `
+		INSERT INTO invitations (organization_id, email, role, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + make_interval(secs => $5))
+		RETURNING ` + invitationColumns
+	row := conn(ctx, r.DB).QueryRow(ctx, query, tenantID(ctx), strings.ToLower(inv.Email), inv.Role, actorID(ctx), ttl.Seconds())
+	if err := scanInvitation(row, inv); err != nil {
+		return fmt.Errorf("failed insert invitation: %w", err)
+	}
+	return nil
+}
+
+// GetPendingInvitations lists the caller's organization invitations that were
+// neither accepted nor revoked, including expired ones.
+func (r *InvitationRepository) GetPendingInvitations(ctx context.Context) ([]Invitation, error) {
+	invitations := []Invitation{}
+
+	query := "SELECT " + invitationColumns + `
+		FROM invitations
+		WHERE organization_id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`
+	rows, err := conn(ctx, r.DB).Query(ctx, query, tenantID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var inv Invitation
+		if err := scanInvitation(rows, &inv); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+func (r *InvitationRepository) RevokeInvitation(ctx context.Context, id string) error {
+	query := `
+		UPDATE invitations SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND organization_id = $2 AND accepted_at IS NULL AND revoked_at IS NULL
+	`
+
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, query, id, tenantID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed revoke: %w", err)
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("invitation not found")
+	}
+
+	return nil
+}
+
+// AcceptInvitation registers u from a pending invitation in one transaction:
+// the user is created with the invited role and, since the invitation link
+// reached their inbox, with a verified email. An email that already has an
+// account is rejected; that user accepts with AcceptInvitationForUser instead.
+func (r *InvitationRepository) AcceptInvitation(ctx context.Context, id string, u *User) error {
+	tx, err := begin(ctx, systemDB(r.DB, r.System))
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	orgID, err := claimInvitation(ctx, tx, id, u.Email, &u.Role)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE lower(email) = lower($1))", u.Email).Scan(&exists); err != nil {
+		return fmt.Errorf("failed check user: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("email already registered")
+	}
+
+	query := `
+		INSERT INTO users (email, password, email_verified_at) VALUES ($1, $2, CURRENT_TIMESTAMP)
+		RETURNING id, email_verified_at
+	`
+	if err := tx.QueryRow(ctx, query, u.Email, u.Password).Scan(&u.ID, &u.EmailVerifiedAt); err != nil {
+		return fmt.Errorf("failed register user: %w", err)
+	}
+
+	if err := addInvitedMember(ctx, tx, id, orgID, u.ID, u.Role); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// AcceptInvitationForUser adds an existing, signed-in user to the inviting
+// organization with the invited role and returns that organization's ID.
+func (r *InvitationRepository) AcceptInvitationForUser(ctx context.Context, id, userID string) (string, error) {
+	tx, err := begin(ctx, systemDB(r.DB, r.System))
+	if err != nil {
+		return "", fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+	if err := tx.QueryRow(ctx, "SELECT email FROM users WHERE id = $1", userID).Scan(&email); err != nil {
+		return "", fmt.Errorf("failed query user: %w", err)
+	}
+
+	var role string
+	orgID, err := claimInvitation(ctx, tx, id, email, &role)
+	if err != nil {
+		return "", err
+	}
+
+	var member bool
+	query := "SELECT EXISTS(SELECT 1 FROM organization_members WHERE organization_id = $1 AND user_id = $2)"
+	if err := tx.QueryRow(ctx, query, orgID, userID).Scan(&member); err != nil {
+		return "", fmt.Errorf("failed check membership: %w", err)
+	}
+	if member {
+		return "", fmt.Errorf("already a member")
+	}
+
+	// The invitation link reached this inbox, so the address is verified too
+	query = "UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP) WHERE id = $1"
+	if _, err := tx.Exec(ctx, query, userID); err != nil {
+		return "", fmt.Errorf("failed verify email: %w", err)
+	}
+
+	if err := addInvitedMember(ctx, tx, id, orgID, userID, role); err != nil {
+		return "", err
+	}
+	return orgID, tx.Commit(ctx)
+}
+
+// claimInvitation locks a pending invitation for email and returns its
+// organization, storing the invited role in role.
+func claimInvitation(ctx context.Context, tx pgx.Tx, id, email string, role *string) (string, error) {
+	var orgID, invited string
+	query := `
+		SELECT organization_id, email, role FROM invitations
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		FOR UPDATE
+	`
+	if err := tx.QueryRow(ctx, query, id).Scan(&orgID, &invited, role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("invitation invalid or expired")
+		}
+		return "", fmt.Errorf("failed query invitation: %w", err)
+	}
+
+	if !strings.EqualFold(invited, email) {
+		return "", fmt.Errorf("invitation is for a different email")
+	}
+	return orgID, nil
+}
+
+// addInvitedMember inserts the membership and marks the invitation accepted.
+func addInvitedMember(ctx context.Context, tx pgx.Tx, id, orgID, userID, role string) error {
+	query := `INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, query, orgID, userID, role); err != nil {
+		return fmt.Errorf("failed insert membership: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE invitations SET accepted_at = CURRENT_TIMESTAMP WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed accept invitation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"inventory-api/internal/logging"
+)
+
+// Login failure scopes
+const (
+	LoginScopeEmail = "email"
+	LoginScopeIP    = "ip"
+)
+
+type LoginFailure struct {
+	Scope        string     `json:"scope"`
+	Key          string     `json:"key"`
+	FailedCount  int        `json:"failed_count"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+}
+
+type LoginAttemptRepository struct {
+	DB *pgxpool.Pool
+}
+
+// ReserveAttempt counts an attempt as a failure before the credentials are
+// checked, so a burst of parallel guesses is throttled one by one instead of
+// all passing a check made before any failure is recorded. retryAfter sees
+// the counter under a row lock; when it returns a positive wait nothing is
+// counted and the wait is returned. Attempts that turn out to be valid are
+// given back with ReleaseAttempt.
+//
+// Anyone can submit any email, so counters of the same scope that are past
+// window and no longer locked are pruned here, keeping the table bounded.
+func (r *LoginAttemptRepository) ReserveAttempt(ctx context.Context, scope, key string, maxFailures int, lockFor, window time.Duration, retryAfter func(LoginFailure) time.Duration) (time.Duration, error) {
+	query := `
+		DELETE FROM login_failures
+		WHERE scope = $1 AND key <> $2
+		AND last_failed_at < CURRENT_TIMESTAMP - make_interval(secs => $3)
+		AND (locked_until IS NULL OR locked_until <= CURRENT_TIMESTAMP)
+	`
+	if _, err := conn(ctx, r.DB).Exec(ctx, query, scope, key, window.Seconds()); err != nil {
+		logging.FromContext(ctx).Warn("Failed prune login failures", "error", err)
+	}
+
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return 0, fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	f := LoginFailure{Scope: scope, Key: key}
+	for attempt := 1; ; attempt++ {
+		// A row must exist to be locked; a new counter starts at zero
+		query = `
+			INSERT INTO login_failures (scope, key, failed_count) VALUES ($1, $2, 0)
+			ON CONFLICT (scope, key) DO NOTHING
+		`
+		if _, err := tx.Exec(ctx, query, scope, key); err != nil {
+			return 0, fmt.Errorf("failed insert login failure: %w", err)
+		}
+
+		query = `SELECT failed_count, last_failed_at, locked_until FROM login_failures WHERE scope = $1 AND key = $2 FOR UPDATE`
+		err := tx.QueryRow(ctx, query, scope, key).Scan(&f.FailedCount, &f.LastFailedAt, &f.LockedUntil)
+		// A concurrent prune can delete the row between the insert and the lock
+		if errors.Is(err, pgx.ErrNoRows) && attempt < 2 {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed lock login failure: %w", err)
+		}
+		break
+	}
+
+	if wait := retryAfter(f); wait > 0 {
+		return wait, nil
+	}
+
+	if _, err := recordFailure(ctx, tx, scope, key, maxFailures, lockFor, window); err != nil {
+		return 0, err
+	}
+	return 0, tx.Commit(ctx)
+}
+
+// ReleaseAttempt takes back an attempt reserved by ReserveAttempt, including
+// a lockout it triggered, once the credentials turned out to be valid.
+func (r *LoginAttemptRepository) ReleaseAttempt(ctx context.Context, scope, key string, maxFailures int) error {
+	query := `
+		UPDATE login_failures SET
+			failed_count = failed_count - 1,
+			locked_until = CASE WHEN failed_count - 1 < $3 THEN NULL ELSE locked_until END
+		WHERE scope = $1 AND key = $2 AND failed_count > 0
+	`
+	if _, err := conn(ctx, r.DB).Exec(ctx, query, scope, key, maxFailures); err != nil {
+		return fmt.Errorf("failed release login attempt: %w", err)
+	}
+
+	query = `DELETE FROM login_failures WHERE scope = $1 AND key = $2 AND failed_count = 0`
+	if _, err := conn(ctx, r.DB).Exec(ctx, query, scope, key); err != nil {
+		return fmt.Errorf("failed release login attempt: %w", err)
+	}
+	return nil
+}
+
+// recordFailure increments the counter and locks the key for lockFor once it
+// reaches maxFailures. Counters start over when the previous failure is older
+// than window or an earlier lock has expired.
+func recordFailure(ctx context.Context, db DBTX, scope, key string, maxFailures int, lockFor, window time.Duration) (LoginFailure, error) {
+	f := LoginFailure{Scope: scope, Key: key}
+
+	query := `
+		WITH prev AS (
+			SELECT CASE
+				WHEN locked_until IS NOT NULL AND locked_until <= CURRENT_TIMESTAMP THEN 0
+				WHEN last_failed_at < CURRENT_TIMESTAMP - make_interval(secs => $4) THEN 0
+				ELSE failed_count
+			END AS failed_count
+			FROM login_failures WHERE scope = $1 AND key = $2
+		), next AS (
+			SELECT COALESCE((SELECT failed_count FROM prev), 0) + 1 AS failed_count
+		)
+		INSERT INTO login_failures (scope, key, failed_count, last_failed_at, locked_until)
+		SELECT $1, $2, next.failed_count, CURRENT_TIMESTAMP,
+			CASE WHEN next.failed_count >= $3 THEN CURRENT_TIMESTAMP + make_interval(secs => $5) END
+		FROM next
+		ON CONFLICT (scope, key) DO UPDATE SET
+			failed_count = EXCLUDED.failed_count,
+			last_failed_at = EXCLUDED.last_failed_at,
+			locked_until = EXCLUDED.locked_until
+		RETURNING failed_count, last_failed_at, locked_until
+	`
+
+	err := db.QueryRow(ctx, query, scope, key, maxFailures, window.Seconds(), lockFor.Seconds()).Scan(&f.FailedCount, &f.LastFailedAt, &f.LockedUntil)
+	if err != nil {
+		return f, fmt.Errorf("failed record login failure: %w", err)
+	}
+	return f, nil
+}
+
+// tenantFailureWhere limits counters to the emails of the caller's members
+// ($1), plus every per-IP counter when $2 is set. Counters carry no
+// organization, so per-IP ones are only shown to deployment operators.
+const tenantFailureWhere = `
+	((scope = 'email' AND key IN (
+		SELECT lower(u.email) FROM users u
+		JOIN organization_members m ON m.user_id = u.id
+		WHERE m.organization_id = $1
+	)) OR (scope = 'ip' AND $2))
+`
+
+// ClearFailure removes the counter after a successful login.
+func (r *LoginAttemptRepository) ClearFailure(ctx context.Context, scope, key string) (bool, error) {
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, `DELETE FROM login_failures WHERE scope = $1 AND key = $2`, scope, key)
+	if err != nil {
+		return false, fmt.Errorf("failed clear login failure: %w", err)
+	}
+	return commandTag.RowsAffected() > 0, nil
+}
+
+// ClearTenantFailure is the admin unlock: it removes the counter only when
+// it belongs to one of the caller's members, or is per-IP and includeIPs is set.
+func (r *LoginAttemptRepository) ClearTenantFailure(ctx context.Context, scope, key string, includeIPs bool) (bool, error) {
+	query := `DELETE FROM login_failures WHERE ` + tenantFailureWhere + ` AND scope = $3 AND key = $4`
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, query, tenantID(ctx), includeIPs, scope, key)
+	if err != nil {
+		return false, fmt.Errorf("failed clear login failure: %w", err)
+	}
+	return commandTag.RowsAffected() > 0, nil
+}
+
+// GetTenantFailures lists the caller's members' counters, plus per-IP ones
+// when includeIPs is set, with currently locked keys first.
+func (r *LoginAttemptRepository) GetTenantFailures(ctx context.Context, lockedOnly, includeIPs bool) ([]LoginFailure, error) {
+	failures := []LoginFailure{}
+
+	query := `
+		SELECT scope, key, failed_count, last_failed_at, locked_until FROM login_failures
+		WHERE ` + tenantFailureWhere + `
+		AND (NOT $3 OR locked_until > CURRENT_TIMESTAMP)
+		ORDER BY locked_until DESC NULLS LAST, last_failed_at DESC
+	`
+	rows, err := conn(ctx, r.DB).Query(ctx, query, tenantID(ctx), includeIPs, lockedOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f LoginFailure
+		if err := rows.Scan(&f.Scope, &f.Key, &f.FailedCount, &f.LastFailedAt, &f.LockedUntil); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
@@ -0,0 +1,372 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Organization is a tenant. Every product, category, customer and the records
+// hanging off them belong to exactly one organization.
+type Organization struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name" validate:"required,max=255"`
+	Slug      string    `json:"slug" validate:"required,max=100,hostname_rfc1123"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Role is the caller's role in the organization, set by GetOrganizationsForUser.
+	Role string `json:"role,omitempty"`
+}
+
+// Membership links a user to an organization with a role that applies there.
+type Membership struct {
+	OrganizationID string `json:"organization_id"`
+	UserID         string `json:"user_id"`
+	Role           string `json:"role"`
+}
+
+// Member is a user as seen by the organization it belongs to.
+type Member struct {
+	ID              string     `json:"id"`
+	Email           string     `json:"email"`
+	OrganizationID  string     `json:"organization_id"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	DeactivatedAt   *time.Time `json:"deactivated_at,omitempty"`
+	JoinedAt        time.Time  `json:"joined_at"`
+}
+
+// MemberFilter narrows GetMembers. Query is a case-insensitive substring of the
+// email; Status is "active", "deactivated" or empty for both.
+type MemberFilter struct {
+	Query   string
+	Role    string
+	Status  string
+	Page    int
+	PerPage int
+}
+
+type OrganizationRepository struct {
+	DB *pgxpool.Pool
+}
+
+// CreateOrganization creates the organization and makes ownerID its first admin.
+func (r *OrganizationRepository) CreateOrganization(ctx context.Context, o *Organization, ownerID string) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO organizations (name, slug) VALUES ($1, $2) RETURNING id, created_at`
+	if err := tx.QueryRow(ctx, query, o.Name, o.Slug).Scan(&o.ID, &o.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("organization slug already taken")
+		}
+		return fmt.Errorf("failed insert organization: %w", err)
+	}
+
+	query = `INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, query, o.ID, ownerID, RoleAdmin); err != nil {
+		return fmt.Errorf("failed insert membership: %w", err)
+	}
+	o.Role = RoleAdmin
+
+	return tx.Commit(ctx)
+}
+
+// BootstrapAdmin creates the first admin of an organization, creating the
+// organization too when its slug does not exist yet. It refuses to run once the
+// organization has an admin, so it cannot be used to take over a live tenant.
+func (r *OrganizationRepository) BootstrapAdmin(ctx context.Context, o *Organization, u *User) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO organizations (name, slug) VALUES ($1, $2)
+		ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug
+		RETURNING id, name, created_at
+	`
+	if err := tx.QueryRow(ctx, query, o.Name, o.Slug).Scan(&o.ID, &o.Name, &o.CreatedAt); err != nil {
+		return fmt.Errorf("failed upsert organization: %w", err)
+	}
+
+	var hasAdmin bool
+	query = "SELECT EXISTS(SELECT 1 FROM organization_members WHERE organization_id = $1 AND role = $2)"
+	if err := tx.QueryRow(ctx, query, o.ID, RoleAdmin).Scan(&hasAdmin); err != nil {
+		return fmt.Errorf("failed check admins: %w", err)
+	}
+	if hasAdmin {
+		return fmt.Errorf("organization already has an admin")
+	}
+
+	query = `
+		INSERT INTO users (email, password, email_verified_at) VALUES ($1, $2, CURRENT_TIMESTAMP)
+		RETURNING id, email_verified_at
+	`
+	if err := tx.QueryRow(ctx, query, u.Email, u.Password).Scan(&u.ID, &u.EmailVerifiedAt); err != nil {
+		return fmt.Errorf("failed insert user: %w", err)
+	}
+
+	u.Role = RoleAdmin
+	query = `INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, query, o.ID, u.ID, u.Role); err != nil {
+		return fmt.Errorf("failed insert membership: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *OrganizationRepository) GetOrganizationBySlug(ctx context.Context, slug string) (Organization, error) {
+	var o Organization
+
+	query := `SELECT id, name, slug, created_at FROM organizations WHERE slug = $1`
+	if err := conn(ctx, r.DB).QueryRow(ctx, query, slug).Scan(&o.ID, &o.Name, &o.Slug, &o.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return o, fmt.Errorf("organization not found")
+		}
+		return o, fmt.Errorf("failed query organization: %w", err)
+	}
+	return o, nil
+}
+
+// GetOrganizationsForUser lists the organizations userID belongs to, oldest membership first.
+func (r *OrganizationRepository) GetOrganizationsForUser(ctx context.Context, userID string) ([]Organization, error) {
+	orgs := []Organization{}
+
+	query := `
+		SELECT o.id, o.name, o.slug, o.created_at, m.role
+		FROM organization_members m
+		JOIN organizations o ON o.id = m.organization_id
+		WHERE m.user_id = $1
+		ORDER BY m.created_at, o.slug
+	`
+	rows, err := conn(ctx, r.DB).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var o Organization
+		if err := rows.Scan(&o.ID, &o.Name, &o.Slug, &o.CreatedAt, &o.Role); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// GetMembership returns userID's active membership in the organization with
+// the given slug. An empty slug picks the user's oldest active membership.
+func (r *OrganizationRepository) GetMembership(ctx context.Context, userID, slug string) (Membership, error) {
+	var m Membership
+
+	query := `
+		SELECT m.organization_id, m.user_id, m.role
+		FROM organization_members m
+		JOIN organizations o ON o.id = m.organization_id
+		WHERE m.user_id = $1 AND ($2 = '' OR o.slug = $2) AND m.deactivated_at IS NULL
+		ORDER BY m.created_at, o.slug
+		LIMIT 1
+	`
+	if err := conn(ctx, r.DB).QueryRow(ctx, query, userID, slug).Scan(&m.OrganizationID, &m.UserID, &m.Role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return m, fmt.Errorf("membership not found")
+		}
+		return m, fmt.Errorf("failed query membership: %w", err)
+	}
+	return m, nil
+}
+
+// GetActiveMembership looks up userID in orgID, reporting "membership not found"
+// when the user was removed or deactivated. The auth middleware calls it on every
+// request so role changes and deactivation take effect immediately.
+func (r *OrganizationRepository) GetActiveMembership(ctx context.Context, userID, orgID string) (Membership, error) {
+	m := Membership{OrganizationID: orgID, UserID: userID}
+
+	query := `
+		SELECT role FROM organization_members
+		WHERE user_id = $1 AND organization_id = $2 AND deactivated_at IS NULL
+	`
+	if err := conn(ctx, r.DB).QueryRow(ctx, query, userID, orgID).Scan(&m.Role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return m, fmt.Errorf("membership not found")
+		}
+		return m, fmt.Errorf("failed query membership: %w", err)
+	}
+	return m, nil
+}
+
+const memberColumns = `u.id, u.email, m.organization_id, m.role, u.email_verified_at, m.deactivated_at, m.created_at`
+
+// memberFilterWhere is shared by the page and count queries of GetMembers.
+// The search value is passed through escapeLike.
+const memberFilterWhere = `
+	WHERE m.organization_id = $1
+	AND ($2 = '' OR u.email ILIKE '%' || $2 || '%' ESCAPE '\')
+	AND ($3 = '' OR m.role = $3)
+	AND ($4 = '' OR ($4 = 'active') = (m.deactivated_at IS NULL))
+`
+
+func scanMember(row pgx.Row, m *Member) error {
+	return row.Scan(&m.ID, &m.Email, &m.OrganizationID, &m.Role, &m.EmailVerifiedAt, &m.DeactivatedAt, &m.JoinedAt)
+}
+
+// GetMembers returns one page of the caller's organization members matching f
+// and the total number of matches.
+func (r *OrganizationRepository) GetMembers(ctx context.Context, f MemberFilter) ([]Member, int, error) {
+	members := []Member{}
+	args := []any{tenantID(ctx), escapeLike(f.Query), f.Role, f.Status}
+
+	from := " FROM organization_members m JOIN users u ON u.id = m.user_id"
+
+	var total int
+	if err := conn(ctx, r.DB).QueryRow(ctx, "SELECT COUNT(*)"+from+memberFilterWhere, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed count: %w", err)
+	}
+
+	query := "SELECT " + memberColumns + from + memberFilterWhere + "ORDER BY u.email LIMIT $5 OFFSET $6"
+	rows, err := conn(ctx, r.DB).Query(ctx, query, append(args, f.PerPage, (f.Page-1)*f.PerPage)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m Member
+		if err := scanMember(rows, &m); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, total, rows.Err()
+}
+
+// GetMember returns userID as a member of the caller's organization.
+func (r *OrganizationRepository) GetMember(ctx context.Context, userID string) (Member, error) {
+	var m Member
+
+	query := "SELECT " + memberColumns + `
+		FROM organization_members m JOIN users u ON u.id = m.user_id
+		WHERE m.user_id = $1 AND m.organization_id = $2
+	`
+	if err := scanMember(conn(ctx, r.DB).QueryRow(ctx, query, userID, tenantID(ctx)), &m); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return m, fmt.Errorf("member not found")
+		}
+		return m, fmt.Errorf("failed query member: %w", err)
+	}
+	return m, nil
+}
+
+// UpdateMemberRole changes userID's role in the caller's organization.
+func (r *OrganizationRepository) UpdateMemberRole(ctx context.Context, userID, role string) error {
+	query := "UPDATE organization_members SET role = $1 WHERE user_id = $2 AND organization_id = $3"
+	return r.changeMember(ctx, query, role, userID)
+}
+
+// SetMemberActive deactivates or reactivates userID in the caller's organization.
+func (r *OrganizationRepository) SetMemberActive(ctx context.Context, userID string, active bool) error {
+	query := `
+		UPDATE organization_members
+		SET deactivated_at = CASE WHEN $1 THEN NULL ELSE COALESCE(deactivated_at, CURRENT_TIMESTAMP) END
+		WHERE user_id = $2 AND organization_id = $3
+	`
+	return r.changeMember(ctx, query, active, userID)
+}
+
+// changeMember runs an UPDATE taking (value, user, organization) against a
+// membership of the caller's organization, refusing to leave it without an active admin.
+func (r *OrganizationRepository) changeMember(ctx context.Context, query string, value any, userID string) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockOrganization(ctx, tx, tenantID(ctx)); err != nil {
+		return err
+	}
+
+	commandTag, err := tx.Exec(ctx, query, value, userID, tenantID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed update member: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("member not found")
+	}
+
+	if err := requireActiveAdmin(ctx, tx, tenantID(ctx)); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RemoveMember removes userID from the caller's organization and deletes the
+// user entirely once it no longer belongs to any organization.
+func (r *OrganizationRepository) RemoveMember(ctx context.Context, userID string) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockOrganization(ctx, tx, tenantID(ctx)); err != nil {
+		return err
+	}
+
+	query := "DELETE FROM organization_members WHERE user_id = $1 AND organization_id = $2"
+	commandTag, err := tx.Exec(ctx, query, userID, tenantID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed delete member: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("member not found")
+	}
+
+	if err := requireActiveAdmin(ctx, tx, tenantID(ctx)); err != nil {
+		return err
+	}
+
+	query = `
+		DELETE FROM users u WHERE u.id = $1
+		AND NOT EXISTS (SELECT 1 FROM organization_members m WHERE m.user_id = u.id)
+	`
+	if _, err := tx.Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed delete user: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lockOrganization serializes membership changes in the caller's organization so
+// two concurrent requests cannot each remove a different last admin.
+func lockOrganization(ctx context.Context, tx pgx.Tx, orgID *string) error {
+	if _, err := tx.Exec(ctx, "SELECT 1 FROM organizations WHERE id = $1 FOR UPDATE", orgID); err != nil {
+		return fmt.Errorf("failed lock organization: %w", err)
+	}
+	return nil
+}
+
+func requireActiveAdmin(ctx context.Context, tx pgx.Tx, orgID *string) error {
+	var admins int
+	query := "SELECT COUNT(*) FROM organization_members WHERE organization_id = $1 AND role = $2 AND deactivated_at IS NULL"
+	if err := tx.QueryRow(ctx, query, orgID, RoleAdmin).Scan(&admins); err != nil {
+		return fmt.Errorf("failed count admins: %w", err)
+	}
+	if admins == 0 {
+		return fmt.Errorf("organization needs an active admin")
+	}
+	return nil
+}
@@ -2,8 +2,12 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"html"
+	"strings"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -14,21 +18,56 @@ type Product struct {
 	Quantity   int    `json:"quantity" validate:"gte=0"`
 	CategoryID string `json:"category_id"`
 
+	// Available is on-hand quantity minus active, unexpired reservations.
+	Available int `json:"available"`
+	// QuarantinedQuantity is returned stock held back from sale pending inspection.
+	QuarantinedQuantity int `json:"quarantined_quantity"`
+
 	CategoryName string `json:"category_name,omitempty"`
+
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
 }
 
+// reservedQuantityExpr sums active, unexpired reservations for the product aliased as p.
+const reservedQuantityExpr = `COALESCE((
+		SELECT SUM(sr.quantity) FROM stock_reservations sr
+		WHERE sr.product_id = p.id AND sr.status = 'active' AND sr.expires_at > CURRENT_TIMESTAMP
+	), 0)`
+
 type ProductRepository struct {
 	DB *pgxpool.Pool
+	// Replica, when set, serves listing and search.
+	Replica *pgxpool.Pool
+	// System runs the cross-tenant inventory stats.
+	System *pgxpool.Pool
 }
 
 func (r *ProductRepository) CreateProduct(ctx context.Context, p *Product) error {
+	db := conn(ctx, r.DB)
+
+	// The foreign key check bypasses row-level security, so the category's
+	// tenant has to be checked here
+	var categoryID *string
+	if p.CategoryID != "" {
+		var exists bool
+		query := "SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1 AND organization_id = $2)"
+		if err := db.QueryRow(ctx, query, p.CategoryID, tenantID(ctx)).Scan(&exists); err != nil {
+			return fmt.Errorf("failed check category: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("category not found")
+		}
+		categoryID = &p.CategoryID
+	}
+
 	query := `
-		INSERT INTO products (name, sku, quantity, category_id)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id
+		INSERT INTO products (organization_id, name, sku, quantity, category_id, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		RETURNING id, COALESCE(created_by::text, '')
 	`
 
-	err := r.DB.QueryRow(ctx, query, p.Name, p.SKU, p.Quantity, p.CategoryID).Scan(&p.ID)
+	err := db.QueryRow(ctx, query, tenantID(ctx), p.Name, p.SKU, p.Quantity, categoryID, actorID(ctx)).Scan(&p.ID, &p.CreatedBy)
 
 	if err != nil {
 		return fmt.Errorf("failed Insert Database: %w", err)
@@ -44,12 +83,16 @@ func (r *ProductRepository) GetAllProducts(ctx context.Context) ([]Product, erro
 	query := `
 	SELECT
 		p.id, p.name, p.sku, p.quantity, p.category_id,
-		COALESCE(c.name, '') as category_name
+		COALESCE(c.name, '') as category_name,
+		p.quantity - ` + reservedQuantityExpr + ` as available,
+		p.quarantined_quantity,
+		COALESCE(p.created_by::text, ''), COALESCE(p.updated_by::text, '')
 	FROM products p
-	LEFT JOIN categories c ON p.category_id = c.id
+	LEFT JOIN categories c ON p.category_id = c.id AND c.organization_id = p.organization_id
+	WHERE p.organization_id = $1
 	`
 
-	rows, err := r.DB.Query(ctx, query)
+	rows, err := readDB(ctx, r.DB, r.Replica).Query(ctx, query, tenantID(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed Query: %w", err)
 	}
@@ -61,7 +104,8 @@ func (r *ProductRepository) GetAllProducts(ctx context.Context) ([]Product, erro
 		// 	return nil, fmt.Errorf("failed to scan: %w", err)
 		// }
 		var catID *string
-		if err := rows.Scan(&p.ID, &p.Name, &p.SKU, &p.Quantity, &catID, &p.CategoryName); err != nil {
+		if err := rows.Scan(&p.ID, &p.Name, &p.SKU, &p.Quantity, &catID, &p.CategoryName, &p.Available, &p.QuarantinedQuantity,
+			&p.CreatedBy, &p.UpdatedBy); err != nil {
 			return nil, fmt.Errorf("failed to scan: %w", err)
 		}
 
@@ -73,11 +117,119 @@ func (r *ProductRepository) GetAllProducts(ctx context.Context) ([]Product, erro
 	return products, nil
 }
 
+type ProductSearchResult struct {
+	Product
+	Rank float64 `json:"rank"`
+	// Highlight is the HTML-escaped name (or SKU) with the matching fragment
+	// wrapped in <mark> tags, safe to render as HTML.
+	Highlight string `json:"highlight"`
+}
+
+// SearchProducts ranks products by full-text match on name and SKU plus trigram
+// similarity, so partial and misspelled terms still find results.
+func (r *ProductRepository) SearchProducts(ctx context.Context, q string, limit int) ([]ProductSearchResult, error) {
+	results := []ProductSearchResult{}
+
+	likePattern := "%" + escapeLike(q) + "%"
+
+	query := `
+	WITH q AS (SELECT websearch_to_tsquery('simple', $1) AS tsq)
+	SELECT
+		p.id, p.name, p.sku, p.quantity, p.category_id,
+		COALESCE(c.name, '') as category_name,
+		p.quantity - ` + reservedQuantityExpr + ` as available,
+		p.quarantined_quantity,
+		ts_rank(p.search_vector, q.tsq)
+			+ GREATEST(word_similarity($1, p.name), similarity(p.sku, $1)) as rank,
+		ts_headline('simple', translate(p.name, E'\x02\x03', ''), q.tsq,
+			E'StartSel="\x02", StopSel="\x03", HighlightAll=true') as highlight
+	FROM products p
+	CROSS JOIN q
+	LEFT JOIN categories c ON p.category_id = c.id AND c.organization_id = p.organization_id
+	WHERE p.organization_id = $4
+		AND (p.search_vector @@ q.tsq
+			OR $1 <% p.name
+			OR p.sku % $1
+			OR p.name ILIKE $2
+			OR p.sku ILIKE $2)
+	ORDER BY rank DESC, p.name
+	LIMIT $3
+	`
+
+	rows, err := readDB(ctx, r.DB, r.Replica).Query(ctx, query, q, likePattern, limit, tenantID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var res ProductSearchResult
+		var catID *string
+		if err := rows.Scan(&res.ID, &res.Name, &res.SKU, &res.Quantity, &catID, &res.CategoryName,
+			&res.Available, &res.QuarantinedQuantity, &res.Rank, &res.Highlight); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+
+		if catID != nil {
+			res.CategoryID = *catID
+		}
+
+		// ts_headline only marks whole-word matches; fall back to the substring
+		res.Highlight = markHeadline(res.Highlight)
+		if !strings.Contains(res.Highlight, "<mark>") {
+			res.Highlight = highlightSubstring(res.Name, q)
+			if !strings.Contains(res.Highlight, "<mark>") {
+				res.Highlight = highlightSubstring(res.SKU, q)
+			}
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// escapeLike escapes LIKE wildcards (and the backslash escape character) so
+// user input is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// headlineMarkers turns the control characters ts_headline is asked to put
+// around matches into <mark> tags, after the rest of the text is escaped.
+var headlineMarkers = strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>")
+
+// markHeadline HTML-escapes a ts_headline result and then inserts the <mark> tags.
+func markHeadline(s string) string {
+	return headlineMarkers.Replace(html.EscapeString(s))
+}
+
+// highlightSubstring HTML-escapes s and wraps the first case-insensitive
+// occurrence of q with <mark> tags.
+func highlightSubstring(s, q string) string {
+	ls, lq := strings.ToLower(s), strings.ToLower(q)
+	// Byte offsets are only valid when lowering does not change lengths
+	if q == "" || len(ls) != len(s) || len(lq) != len(q) {
+		return html.EscapeString(s)
+	}
+
+	i := strings.Index(ls, lq)
+	if i < 0 {
+		return html.EscapeString(s)
+	}
+	return html.EscapeString(s[:i]) + "<mark>" + html.EscapeString(s[i:i+len(q)]) + "</mark>" + html.EscapeString(s[i+len(q):])
+}
+
 func (r *ProductRepository) GetProductByID(ctx context.Context, id string) (Product, error) {
 	var p Product
-	query := "SELECT id, name, sku, quantity FROM products WHERE id = $1"
+	query := `
+	SELECT p.id, p.name, p.sku, p.quantity, p.quantity - ` + reservedQuantityExpr + ` as available,
+		p.quarantined_quantity,
+		COALESCE(p.created_by::text, ''), COALESCE(p.updated_by::text, '')
+	FROM products p
+	WHERE p.id = $1 AND p.organization_id = $2
+	`
 
-	err := r.DB.QueryRow(ctx, query, id).Scan(&p.ID, &p.Name, &p.SKU, &p.Quantity)
+	err := conn(ctx, r.DB).QueryRow(ctx, query, id, tenantID(ctx)).Scan(&p.ID, &p.Name, &p.SKU, &p.Quantity, &p.Available, &p.QuarantinedQuantity,
+		&p.CreatedBy, &p.UpdatedBy)
 	if err != nil {
 		return p, err
 	}
@@ -85,25 +237,83 @@ func (r *ProductRepository) GetProductByID(ctx context.Context, id string) (Prod
 	return p, nil
 }
 
+// UpdateProduct saves the product. A changed quantity is recorded as a manual
+// adjustment movement in the same transaction, so the ledger keeps adding up
+// to the on-hand quantity.
 func (r *ProductRepository) UpdateProduct(ctx context.Context, id string, p *Product) error {
-	query := "UPDATE products SET name=$1, sku=$2, quantity=$3 WHERE id=$4"
-
-	commandTag, err := r.DB.Exec(ctx, query, p.Name, p.SKU, p.Quantity, id)
+	tx, err := begin(ctx, r.DB)
 	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var current int
+	query := "SELECT quantity FROM products WHERE id = $1 AND organization_id = $2 FOR UPDATE"
+	if err := tx.QueryRow(ctx, query, id, tenantID(ctx)).Scan(&current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("product not found")
+		}
+		return fmt.Errorf("failed lock product: %w", err)
+	}
+
+	// Reserved units must stay on hand, otherwise available goes negative
+	var reserved int
+	query = `
+		SELECT COALESCE(SUM(quantity), 0) FROM stock_reservations
+		WHERE product_id = $1 AND status = 'active' AND expires_at > CURRENT_TIMESTAMP
+	`
+	if err := tx.QueryRow(ctx, query, id).Scan(&reserved); err != nil {
+		return fmt.Errorf("failed sum reservations: %w", err)
+	}
+	if p.Quantity < reserved {
+		return fmt.Errorf("quantity below reserved")
+	}
+
+	query = "UPDATE products SET name=$1, sku=$2, quantity=$3, updated_by=$4 WHERE id=$5 AND organization_id=$6"
+	if _, err := tx.Exec(ctx, query, p.Name, p.SKU, p.Quantity, actorID(ctx), id, tenantID(ctx)); err != nil {
 		return fmt.Errorf("failed Update: %w", err)
 	}
 
-	if commandTag.RowsAffected() == 0 {
-		return fmt.Errorf("product not found")
+	if change := p.Quantity - current; change != 0 {
+		movement := StockMovement{
+			ProductID:      id,
+			QuantityChange: change,
+			Reason:         MovementManualAdjustment,
+		}
+		if err := insertStockMovement(ctx, tx, &movement); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return tx.Commit(ctx)
+}
+
+// ReassignCategory moves every product in category from to category to and
+// returns how many were moved. Run it in the same WithTx as deleting from.
+func (r *ProductRepository) ReassignCategory(ctx context.Context, from, to string) (int64, error) {
+	db := conn(ctx, r.DB)
+
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1 AND organization_id = $2)"
+	if err := db.QueryRow(ctx, query, to, tenantID(ctx)).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed check category: %w", err)
+	}
+	if !exists {
+		return 0, fmt.Errorf("target category not found")
+	}
+
+	query = "UPDATE products SET category_id=$1, updated_by=$2 WHERE category_id=$3 AND organization_id=$4"
+	commandTag, err := db.Exec(ctx, query, to, actorID(ctx), from, tenantID(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed reassign products: %w", err)
+	}
+	return commandTag.RowsAffected(), nil
 }
 
 func (r *ProductRepository) DeleteProduct(ctx context.Context, id string) error {
-	query := "DELETE FROM products WHERE id=$1"
+	query := "DELETE FROM products WHERE id=$1 AND organization_id=$2"
 
-	commandTag, err := r.DB.Exec(ctx, query, id)
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, query, id, tenantID(ctx))
 	if err != nil {
 		return fmt.Errorf("failed delete: %w", err)
 	}
@@ -114,3 +324,41 @@ func (r *ProductRepository) DeleteProduct(ctx context.Context, id string) error
 
 	return nil
 }
+
+// InventoryStats summarizes one organization's catalog for monitoring.
+type InventoryStats struct {
+	Organization string
+	Products     int
+	// LowStock counts products whose available quantity is at or below the threshold.
+	LowStock int
+}
+
+// GetInventoryStats returns catalog totals per organization slug. It is a
+// system query for the metrics endpoint and runs across all organizations.
+func (r *ProductRepository) GetInventoryStats(ctx context.Context, lowStockThreshold int) ([]InventoryStats, error) {
+	stats := []InventoryStats{}
+
+	query := `
+	SELECT o.slug,
+		COUNT(p.id),
+		COUNT(p.id) FILTER (WHERE p.quantity - ` + reservedQuantityExpr + ` <= $1)
+	FROM organizations o
+	LEFT JOIN products p ON p.organization_id = o.id
+	GROUP BY o.slug
+	`
+
+	rows, err := systemDB(r.DB, r.System).Query(ctx, query, lowStockThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("failed Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s InventoryStats
+		if err := rows.Scan(&s.Organization, &s.Products, &s.LowStock); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,49 @@
+package repository
+
+import "testing"
+
+func TestHighlightSubstring(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		q    string
+		want string
+	}{
+		{"middle", "Kabel USB-C", "usb", "Kabel <mark>USB</mark>-C"},
+		{"start", "Mouse Wireless", "mou", "<mark>Mou</mark>se Wireless"},
+		{"whole string", "SKU-001", "sku-001", "<mark>SKU-001</mark>"},
+		{"first occurrence only", "aa aa", "aa", "<mark>aa</mark> aa"},
+		{"no match", "Keyboard", "mouse", "Keyboard"},
+		{"empty query", "Keyboard", "", "Keyboard"},
+		{"name is escaped", `<b>Bolt</b> & "Nut"`, "bolt", `&lt;b&gt;<mark>Bolt</mark>&lt;/b&gt; &amp; &#34;Nut&#34;`},
+		{"match is escaped", "Tom & Jerry", "& j", "Tom <mark>&amp; J</mark>erry"},
+		{"escaped without match", "<script>", "x", "&lt;script&gt;"},
+		// Lowering "İ" changes its byte length, so offsets cannot be trusted
+		{"length-changing case fold", "İstanbul <x>", "stan", "İstanbul &lt;x&gt;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightSubstring(tt.s, tt.q); got != tt.want {
+				t.Errorf("highlightSubstring(%q, %q) = %q, want %q", tt.s, tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkHeadline(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Kabel \x02USB\x03 C", "Kabel <mark>USB</mark> C"},
+		{"\x02<b>\x03 & co", "<mark>&lt;b&gt;</mark> &amp; co"},
+		{"no match", "no match"},
+	}
+
+	for _, tt := range tests {
+		if got := markHeadline(tt.in); got != tt.want {
+			t.Errorf("markHeadline(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
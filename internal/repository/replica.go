@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// readDB picks where read-only queries that tolerate replication lag
+// (listings, search, reports) run: the replica when one is configured,
+// otherwise the primary. Inside a unit of work they use its transaction, so
+// they see its uncommitted writes. Writes and reads that must see a write just
+// made stay on the primary.
+func readDB(ctx context.Context, primary, replica *pgxpool.Pool) DBTX {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok || replica == nil {
+		return conn(ctx, primary)
+	}
+	return replica
+}
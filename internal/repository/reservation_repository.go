@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Reservation struct {
+	ID         string `json:"id"`
+	ProductID  string `json:"product_id" validate:"required,uuid"`
+	CustomerID string `json:"customer_id,omitempty" validate:"omitempty,uuid"`
+	Quantity   int    `json:"quantity" validate:"required,gt=0"`
+	// TTLSeconds is capped at 30 days so stock cannot be held indefinitely.
+	TTLSeconds int        `json:"ttl_seconds,omitempty" validate:"gte=0,lte=2592000"`
+	Status     string     `json:"status"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
+type ReservationRepository struct {
+	DB *pgxpool.Pool
+	// System runs the sweeper across every organization.
+	System *pgxpool.Pool
+}
+
+const reservationColumns = `id, product_id, COALESCE(customer_id::text, ''), quantity, status, expires_at, created_at, released_at`
+
+func scanReservation(row pgx.Row, res *Reservation) error {
+	return row.Scan(&res.ID, &res.ProductID, &res.CustomerID, &res.Quantity, &res.Status, &res.ExpiresAt, &res.CreatedAt, &res.ReleasedAt)
+}
+
+// CreateReservation holds stock for a product. The product row is locked so two
+// concurrent reservations cannot both claim the last available units.
+func (r *ReservationRepository) CreateReservation(ctx context.Context, res *Reservation) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var onHand int
+	query := "SELECT quantity FROM products WHERE id = $1 AND organization_id = $2 FOR UPDATE"
+	err = tx.QueryRow(ctx, query, res.ProductID, tenantID(ctx)).Scan(&onHand)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("product not found")
+		}
+		return fmt.Errorf("failed lock product: %w", err)
+	}
+
+	var reserved int
+	query = `
+		SELECT COALESCE(SUM(quantity), 0) FROM stock_reservations
+		WHERE product_id = $1 AND status = 'active' AND expires_at > CURRENT_TIMESTAMP
+	`
+	if err := tx.QueryRow(ctx, query, res.ProductID).Scan(&reserved); err != nil {
+		return fmt.Errorf("failed sum reservations: %w", err)
+	}
+
+	if onHand-reserved < res.Quantity {
+		return fmt.Errorf("insufficient stock")
+	}
+
+	var customerID *string
+	if res.CustomerID != "" {
+		var exists bool
+		query = "SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1 AND organization_id = $2)"
+		if err := tx.QueryRow(ctx, query, res.CustomerID, tenantID(ctx)).Scan(&exists); err != nil {
+			return fmt.Errorf("failed check customer: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("customer not found")
+		}
+		customerID = &res.CustomerID
+	}
+
+	query = `
+		INSERT INTO stock_reservations (organization_id, product_id, customer_id, quantity, expires_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + make_interval(secs => $5))
+		RETURNING ` + reservationColumns
+	row := tx.QueryRow(ctx, query, tenantID(ctx), res.ProductID, customerID, res.Quantity, res.TTLSeconds)
+	if err := scanReservation(row, res); err != nil {
+		return fmt.Errorf("failed insert reservation: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *ReservationRepository) GetAllReservations(ctx context.Context, productID string, activeOnly bool) ([]Reservation, error) {
+	reservations := []Reservation{}
+
+	query := `
+		SELECT ` + reservationColumns + ` FROM stock_reservations
+		WHERE ($1 = '' OR product_id::text = $1)
+		AND (NOT $2 OR (status = 'active' AND expires_at > CURRENT_TIMESTAMP))
+		AND organization_id = $3
+		ORDER BY created_at DESC
+	`
+	rows, err := conn(ctx, r.DB).Query(ctx, query, productID, activeOnly, tenantID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var res Reservation
+		if err := scanReservation(rows, &res); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		reservations = append(reservations, res)
+	}
+	return reservations, rows.Err()
+}
+
+// ReleaseReservation frees the held stock without deducting it.
+func (r *ReservationRepository) ReleaseReservation(ctx context.Context, id string) error {
+	query := `
+		UPDATE stock_reservations SET status = 'released', released_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'active' AND organization_id = $2
+	`
+
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, query, id, tenantID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed release: %w", err)
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("reservation not found")
+	}
+
+	return nil
+}
+
+// FulfillReservation turns the hold into a real deduction from products.quantity,
+// e.g. when the reserved goods are shipped.
+func (r *ReservationRepository) FulfillReservation(ctx context.Context, id string) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var productID string
+	var quantity int
+	query := `
+		UPDATE stock_reservations SET status = 'fulfilled', released_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'active' AND expires_at > CURRENT_TIMESTAMP AND organization_id = $2
+		RETURNING product_id, quantity
+	`
+	if err := tx.QueryRow(ctx, query, id, tenantID(ctx)).Scan(&productID, &quantity); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("reservation not found")
+		}
+		return fmt.Errorf("failed fulfill: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE products SET quantity = quantity - $1 WHERE id = $2", quantity, productID)
+	if err != nil {
+		return fmt.Errorf("failed deduct stock: %w", err)
+	}
+
+	movement := StockMovement{
+		ProductID:      productID,
+		QuantityChange: -quantity,
+		Reason:         MovementReservationFulfilled,
+		ReferenceID:    id,
+	}
+	if err := insertStockMovement(ctx, tx, &movement); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ReleaseExpiredReservations marks every active reservation past its expiry as
+// expired and returns how many were released. It is a system job and runs
+// across all organizations.
+func (r *ReservationRepository) ReleaseExpiredReservations(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE stock_reservations SET status = 'expired', released_at = CURRENT_TIMESTAMP
+		WHERE status = 'active' AND expires_at <= CURRENT_TIMESTAMP
+	`
+
+	commandTag, err := systemDB(r.DB, r.System).Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed release expired reservations: %w", err)
+	}
+
+	return commandTag.RowsAffected(), nil
+}
@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Return line dispositions
+const (
+	DispositionRestock    = "restock"
+	DispositionQuarantine = "quarantine"
+	DispositionScrap      = "scrap"
+)
+
+type Return struct {
+	ID         string `json:"id"`
+	CustomerID string `json:"customer_id" validate:"required,uuid"`
+	// OrderReference is free text until orders are modelled in this service.
+	OrderReference string       `json:"order_reference,omitempty" validate:"max=100"`
+	Reason         string       `json:"reason" validate:"required"`
+	CreatedAt      time.Time    `json:"created_at"`
+	Lines          []ReturnLine `json:"lines,omitempty" validate:"required,min=1,dive"`
+}
+
+type ReturnLine struct {
+	ID          string `json:"id"`
+	ProductID   string `json:"product_id" validate:"required,uuid"`
+	Quantity    int    `json:"quantity" validate:"required,gt=0"`
+	Disposition string `json:"disposition" validate:"required,oneof=restock quarantine scrap"`
+	Reason      string `json:"reason,omitempty"`
+	// ResolvedQuantity is how much of a quarantine line has been released or scrapped.
+	ResolvedQuantity int `json:"resolved_quantity"`
+}
+
+// Quarantine resolutions
+const (
+	ResolutionRelease = "release"
+	ResolutionScrap   = "scrap"
+)
+
+// QuarantineResolution settles quarantined units of a return line: release
+// puts them back on sale, scrap writes them off.
+type QuarantineResolution struct {
+	Action   string `json:"action" validate:"required,oneof=release scrap"`
+	Quantity int    `json:"quantity" validate:"required,gt=0"`
+	Note     string `json:"note,omitempty"`
+}
+
+type ReturnRepository struct {
+	DB *pgxpool.Pool
+	// Replica, when set, serves GetAllReturns.
+	Replica *pgxpool.Pool
+}
+
+// CreateReturn records a customer return and applies every line's disposition
+// to product stock in the same transaction:
+// restock adds to quantity, quarantine adds to quarantined_quantity, and scrap
+// only records the line.
+func (r *ReturnRepository) CreateReturn(ctx context.Context, ret *Return) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1 AND organization_id = $2)"
+	err = tx.QueryRow(ctx, query, ret.CustomerID, tenantID(ctx)).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed check customer: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("customer not found")
+	}
+
+	query = `
+		INSERT INTO returns (organization_id, customer_id, order_reference, reason)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	if err := tx.QueryRow(ctx, query, tenantID(ctx), ret.CustomerID, ret.OrderReference, ret.Reason).Scan(&ret.ID, &ret.CreatedAt); err != nil {
+		return fmt.Errorf("failed insert return: %w", err)
+	}
+
+	for i := range ret.Lines {
+		line := &ret.Lines[i]
+
+		var exists bool
+		query := "SELECT EXISTS(SELECT 1 FROM products WHERE id = $1 AND organization_id = $2)"
+		err := tx.QueryRow(ctx, query, line.ProductID, tenantID(ctx)).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed check product: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("product not found")
+		}
+
+		switch line.Disposition {
+		case DispositionRestock:
+			_, err = tx.Exec(ctx, "UPDATE products SET quantity = quantity + $1 WHERE id = $2", line.Quantity, line.ProductID)
+		case DispositionQuarantine:
+			_, err = tx.Exec(ctx, "UPDATE products SET quarantined_quantity = quarantined_quantity + $1 WHERE id = $2", line.Quantity, line.ProductID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed update stock: %w", err)
+		}
+
+		query = `
+			INSERT INTO return_lines (organization_id, return_id, product_id, quantity, disposition, reason)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`
+		err = tx.QueryRow(ctx, query, tenantID(ctx), ret.ID, line.ProductID, line.Quantity, line.Disposition, line.Reason).Scan(&line.ID)
+		if err != nil {
+			return fmt.Errorf("failed insert return line: %w", err)
+		}
+
+		movement := StockMovement{
+			ProductID:   line.ProductID,
+			ReferenceID: ret.ID,
+			Note:        line.Reason,
+		}
+		switch line.Disposition {
+		case DispositionRestock:
+			movement.QuantityChange, movement.Reason = line.Quantity, MovementReturnRestock
+		case DispositionQuarantine:
+			movement.QuarantinedChange, movement.Reason = line.Quantity, MovementReturnQuarantine
+		default:
+			continue
+		}
+		if err := insertStockMovement(ctx, tx, &movement); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *ReturnRepository) GetAllReturns(ctx context.Context, customerID string) ([]Return, error) {
+	returns := []Return{}
+
+	query := `
+		SELECT id, customer_id, order_reference, reason, created_at
+		FROM returns
+		WHERE ($1 = '' OR customer_id::text = $1)
+		AND organization_id = $2
+		ORDER BY created_at DESC
+	`
+	rows, err := readDB(ctx, r.DB, r.Replica).Query(ctx, query, customerID, tenantID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ret Return
+		if err := rows.Scan(&ret.ID, &ret.CustomerID, &ret.OrderReference, &ret.Reason, &ret.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		returns = append(returns, ret)
+	}
+	return returns, rows.Err()
+}
+
+func (r *ReturnRepository) GetReturnByID(ctx context.Context, id string) (Return, error) {
+	var ret Return
+
+	query := "SELECT id, customer_id, order_reference, reason, created_at FROM returns WHERE id = $1 AND organization_id = $2"
+	err := conn(ctx, r.DB).QueryRow(ctx, query, id, tenantID(ctx)).Scan(&ret.ID, &ret.CustomerID, &ret.OrderReference, &ret.Reason, &ret.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ret, fmt.Errorf("return not found")
+		}
+		return ret, fmt.Errorf("failed query return: %w", err)
+	}
+
+	rows, err := conn(ctx, r.DB).Query(ctx, "SELECT id, product_id, quantity, disposition, reason, resolved_quantity FROM return_lines WHERE return_id = $1", id)
+	if err != nil {
+		return ret, fmt.Errorf("failed query return lines: %w", err)
+	}
+	defer rows.Close()
+
+	ret.Lines = []ReturnLine{}
+	for rows.Next() {
+		var line ReturnLine
+		if err := rows.Scan(&line.ID, &line.ProductID, &line.Quantity, &line.Disposition, &line.Reason, &line.ResolvedQuantity); err != nil {
+			return ret, fmt.Errorf("failed to scan: %w", err)
+		}
+		ret.Lines = append(ret.Lines, line)
+	}
+	return ret, rows.Err()
+}
+
+// ResolveQuarantine releases or scraps quarantined units of a return line.
+// Release moves them from quarantined_quantity back to quantity, scrap only
+// takes them out of quarantine; either way a movement is recorded against the return.
+func (r *ReturnRepository) ResolveQuarantine(ctx context.Context, returnID, lineID string, res QuarantineResolution) (StockMovement, error) {
+	var movement StockMovement
+
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return movement, fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var productID, disposition string
+	var quantity, resolved int
+	query := `
+		SELECT product_id, disposition, quantity, resolved_quantity FROM return_lines
+		WHERE id = $1 AND return_id = $2 AND organization_id = $3
+		FOR UPDATE
+	`
+	err = tx.QueryRow(ctx, query, lineID, returnID, tenantID(ctx)).Scan(&productID, &disposition, &quantity, &resolved)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return movement, fmt.Errorf("return line not found")
+		}
+		return movement, fmt.Errorf("failed lock return line: %w", err)
+	}
+	if disposition != DispositionQuarantine {
+		return movement, fmt.Errorf("return line not quarantined")
+	}
+	if res.Quantity > quantity-resolved {
+		return movement, fmt.Errorf("quantity exceeds quarantined")
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE return_lines SET resolved_quantity = resolved_quantity + $1 WHERE id = $2", res.Quantity, lineID); err != nil {
+		return movement, fmt.Errorf("failed update return line: %w", err)
+	}
+
+	movement = StockMovement{
+		ProductID:         productID,
+		QuarantinedChange: -res.Quantity,
+		Reason:            MovementQuarantineScrap,
+		ReferenceID:       returnID,
+		Note:              res.Note,
+	}
+	if res.Action == ResolutionRelease {
+		movement.QuantityChange, movement.Reason = res.Quantity, MovementQuarantineRelease
+	}
+
+	query = `
+		UPDATE products SET quarantined_quantity = quarantined_quantity - $1, quantity = quantity + $2
+		WHERE id = $3 AND organization_id = $4 AND quarantined_quantity >= $1
+	`
+	commandTag, err := tx.Exec(ctx, query, res.Quantity, movement.QuantityChange, productID, tenantID(ctx))
+	if err != nil {
+		return movement, fmt.Errorf("failed update stock: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return movement, fmt.Errorf("quantity exceeds quarantined")
+	}
+
+	if err := insertStockMovement(ctx, tx, &movement); err != nil {
+		return movement, err
+	}
+
+	return movement, tx.Commit(ctx)
+}
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StockCount struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name" validate:"required"`
+	CategoryID string     `json:"category_id,omitempty" validate:"omitempty,uuid"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ClosedAt   *time.Time `json:"closed_at,omitempty"`
+
+	Lines []StockCountLine `json:"lines,omitempty"`
+}
+
+type StockCountLine struct {
+	ProductID        string     `json:"product_id"`
+	ProductName      string     `json:"product_name"`
+	SKU              string     `json:"sku"`
+	ExpectedQuantity int        `json:"expected_quantity"`
+	CountedQuantity  *int       `json:"counted_quantity"`
+	Variance         *int       `json:"variance"`
+	CountedAt        *time.Time `json:"counted_at,omitempty"`
+}
+
+type CountEntry struct {
+	SKU             string `json:"sku" validate:"required"`
+	CountedQuantity int    `json:"counted_quantity" validate:"gte=0"`
+}
+
+type StockCountRepository struct {
+	DB *pgxpool.Pool
+	// Replica, when set, serves GetAllStockCounts.
+	Replica *pgxpool.Pool
+}
+
+// CreateStockCount opens a session and freezes the current quantity of every
+// product (optionally limited to one category) as the expected quantity.
+func (r *StockCountRepository) CreateStockCount(ctx context.Context, sc *StockCount) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var categoryID *string
+	if sc.CategoryID != "" {
+		categoryID = &sc.CategoryID
+	}
+
+	query := `
+		INSERT INTO stock_counts (organization_id, name, category_id) VALUES ($1, $2, $3)
+		RETURNING id, status, created_at
+	`
+	if err := tx.QueryRow(ctx, query, tenantID(ctx), sc.Name, categoryID).Scan(&sc.ID, &sc.Status, &sc.CreatedAt); err != nil {
+		return fmt.Errorf("failed insert stock count: %w", err)
+	}
+
+	query = `
+		INSERT INTO stock_count_lines (organization_id, stock_count_id, product_id, sku, expected_quantity)
+		SELECT organization_id, $1, id, sku, COALESCE(quantity, 0) FROM products
+		WHERE organization_id = $3 AND ($2::uuid IS NULL OR category_id = $2)
+	`
+	if _, err := tx.Exec(ctx, query, sc.ID, categoryID, tenantID(ctx)); err != nil {
+		return fmt.Errorf("failed snapshot products: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *StockCountRepository) GetAllStockCounts(ctx context.Context) ([]StockCount, error) {
+	counts := []StockCount{}
+
+	query := `
+		SELECT id, name, COALESCE(category_id::text, ''), status, created_at, closed_at
+		FROM stock_counts WHERE organization_id = $1 ORDER BY created_at DESC
+	`
+	rows, err := readDB(ctx, r.DB, r.Replica).Query(ctx, query, tenantID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sc StockCount
+		if err := rows.Scan(&sc.ID, &sc.Name, &sc.CategoryID, &sc.Status, &sc.CreatedAt, &sc.ClosedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		counts = append(counts, sc)
+	}
+	return counts, rows.Err()
+}
+
+// GetStockCountByID returns the session with its lines. When varianceOnly is set,
+// only counted lines whose quantity differs from the snapshot are included.
+func (r *StockCountRepository) GetStockCountByID(ctx context.Context, id string, varianceOnly bool) (StockCount, error) {
+	var sc StockCount
+
+	query := `
+		SELECT id, name, COALESCE(category_id::text, ''), status, created_at, closed_at
+		FROM stock_counts WHERE id = $1 AND organization_id = $2
+	`
+	err := conn(ctx, r.DB).QueryRow(ctx, query, id, tenantID(ctx)).Scan(&sc.ID, &sc.Name, &sc.CategoryID, &sc.Status, &sc.CreatedAt, &sc.ClosedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sc, fmt.Errorf("stock count not found")
+		}
+		return sc, fmt.Errorf("failed query stock count: %w", err)
+	}
+
+	query = `
+		SELECT l.product_id, p.name, l.sku, l.expected_quantity, l.counted_quantity, l.counted_at
+		FROM stock_count_lines l
+		JOIN products p ON p.id = l.product_id
+		WHERE l.stock_count_id = $1
+		AND (NOT $2 OR (l.counted_quantity IS NOT NULL AND l.counted_quantity <> l.expected_quantity))
+		ORDER BY l.sku
+	`
+	rows, err := conn(ctx, r.DB).Query(ctx, query, id, varianceOnly)
+	if err != nil {
+		return sc, fmt.Errorf("failed query lines: %w", err)
+	}
+	defer rows.Close()
+
+	sc.Lines = []StockCountLine{}
+	for rows.Next() {
+		var l StockCountLine
+		if err := rows.Scan(&l.ProductID, &l.ProductName, &l.SKU, &l.ExpectedQuantity, &l.CountedQuantity, &l.CountedAt); err != nil {
+			return sc, fmt.Errorf("failed to scan: %w", err)
+		}
+		if l.CountedQuantity != nil {
+			variance := *l.CountedQuantity - l.ExpectedQuantity
+			l.Variance = &variance
+		}
+		sc.Lines = append(sc.Lines, l)
+	}
+	return sc, rows.Err()
+}
+
+// RecordCounts stores counted quantities by SKU on an open session.
+func (r *StockCountRepository) RecordCounts(ctx context.Context, id string, entries []CountEntry) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockOpenStockCount(ctx, tx, id); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE stock_count_lines SET counted_quantity = $1, counted_at = CURRENT_TIMESTAMP
+		WHERE stock_count_id = $2 AND sku = $3
+	`
+	for _, e := range entries {
+		commandTag, err := tx.Exec(ctx, query, e.CountedQuantity, id, e.SKU)
+		if err != nil {
+			return fmt.Errorf("failed record count: %w", err)
+		}
+		if commandTag.RowsAffected() == 0 {
+			return fmt.Errorf("sku %s not in stock count", e.SKU)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ApproveStockCount posts one count_adjustment movement per variance and applies
+// it to products.quantity, all in one transaction. The variance is applied as a
+// delta so movements recorded after the snapshot are preserved.
+func (r *StockCountRepository) ApproveStockCount(ctx context.Context, id string) ([]StockMovement, error) {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockOpenStockCount(ctx, tx, id); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT product_id, counted_quantity - expected_quantity
+		FROM stock_count_lines
+		WHERE stock_count_id = $1
+		AND counted_quantity IS NOT NULL AND counted_quantity <> expected_quantity
+		ORDER BY product_id
+	`
+	rows, err := tx.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed query variances: %w", err)
+	}
+
+	movements := []StockMovement{}
+	for rows.Next() {
+		m := StockMovement{Reason: MovementCountAdjustment, ReferenceID: id}
+		if err := rows.Scan(&m.ProductID, &m.QuantityChange); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		movements = append(movements, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed read variances: %w", err)
+	}
+
+	// Products are locked in a fixed order. Stock sold or reserved since the
+	// count was opened can make a variance invalid, so the new quantity must
+	// still cover the active reservations
+	for i := range movements {
+		m := &movements[i]
+
+		var onHand, reserved int
+		query = "SELECT quantity FROM products WHERE id = $1 AND organization_id = $2 FOR UPDATE"
+		if err := tx.QueryRow(ctx, query, m.ProductID, tenantID(ctx)).Scan(&onHand); err != nil {
+			return nil, fmt.Errorf("failed lock product: %w", err)
+		}
+		query = `
+			SELECT COALESCE(SUM(quantity), 0) FROM stock_reservations
+			WHERE product_id = $1 AND status = 'active' AND expires_at > CURRENT_TIMESTAMP
+		`
+		if err := tx.QueryRow(ctx, query, m.ProductID).Scan(&reserved); err != nil {
+			return nil, fmt.Errorf("failed sum reservations: %w", err)
+		}
+		if next := onHand + m.QuantityChange; next < 0 || next < reserved {
+			return nil, fmt.Errorf("quantity below reserved")
+		}
+
+		_, err := tx.Exec(ctx, "UPDATE products SET quantity = quantity + $1 WHERE id = $2", m.QuantityChange, m.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("failed adjust product: %w", err)
+		}
+		if err := insertStockMovement(ctx, tx, m); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE stock_counts SET status = 'approved', closed_at = CURRENT_TIMESTAMP WHERE id = $1", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed approve stock count: %w", err)
+	}
+
+	return movements, tx.Commit(ctx)
+}
+
+func (r *StockCountRepository) CancelStockCount(ctx context.Context, id string) error {
+	query := `
+		UPDATE stock_counts SET status = 'cancelled', closed_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'open' AND organization_id = $2
+	`
+
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, query, id, tenantID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed cancel: %w", err)
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("stock count not open")
+	}
+
+	return nil
+}
+
+func lockOpenStockCount(ctx context.Context, tx pgx.Tx, id string) error {
+	var status string
+	query := "SELECT status FROM stock_counts WHERE id = $1 AND organization_id = $2 FOR UPDATE"
+	err := tx.QueryRow(ctx, query, id, tenantID(ctx)).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("stock count not found")
+		}
+		return fmt.Errorf("failed lock stock count: %w", err)
+	}
+
+	if status != "open" {
+		return fmt.Errorf("stock count not open")
+	}
+	return nil
+}
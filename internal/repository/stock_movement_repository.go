@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Movement reasons recorded in stock_movements.reason
+const (
+	MovementInitialStock         = "initial_stock"
+	MovementManualAdjustment     = "manual_adjustment"
+	MovementCountAdjustment      = "count_adjustment"
+	MovementReservationFulfilled = "reservation_fulfilled"
+	MovementReturnRestock        = "return_restock"
+	MovementReturnQuarantine     = "return_quarantine"
+	MovementQuarantineRelease    = "quarantine_release"
+	MovementQuarantineScrap      = "quarantine_scrap"
+)
+
+type StockMovement struct {
+	ID             string `json:"id"`
+	ProductID      string `json:"product_id"`
+	QuantityChange int    `json:"quantity_change"`
+	// QuarantinedChange is the change to quarantined_quantity, which is not for sale.
+	QuarantinedChange int       `json:"quarantined_change"`
+	Reason            string    `json:"reason"`
+	ReferenceID       string    `json:"reference_id,omitempty"`
+	Note              string    `json:"note,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type StockMovementRepository struct {
+	DB *pgxpool.Pool
+	// Replica, when set, serves the movement history.
+	Replica *pgxpool.Pool
+}
+
+// insertStockMovement records a movement inside the caller's transaction, so the
+// ledger entry and the products.quantity change commit together.
+func insertStockMovement(ctx context.Context, tx DBTX, m *StockMovement) error {
+	var referenceID *string
+	if m.ReferenceID != "" {
+		referenceID = &m.ReferenceID
+	}
+
+	query := `
+		INSERT INTO stock_movements (organization_id, product_id, quantity_change, quarantined_change, reason, reference_id, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	err := tx.QueryRow(ctx, query, tenantID(ctx), m.ProductID, m.QuantityChange, m.QuarantinedChange, m.Reason, referenceID, m.Note).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed insert stock movement: %w", err)
+	}
+	return nil
+}
+
+// CreateMovement records a movement whose quantity change the caller has
+// already applied; run it in the same WithTx as that change.
+func (r *StockMovementRepository) CreateMovement(ctx context.Context, m *StockMovement) error {
+	return insertStockMovement(ctx, conn(ctx, r.DB), m)
+}
+
+func (r *StockMovementRepository) GetMovementsByProduct(ctx context.Context, productID string) ([]StockMovement, error) {
+	movements := []StockMovement{}
+
+	query := `
+		SELECT id, product_id, quantity_change, quarantined_change, reason, COALESCE(reference_id::text, ''), note, created_at
+		FROM stock_movements
+		WHERE product_id = $1 AND organization_id = $2
+		ORDER BY created_at DESC
+	`
+	rows, err := readDB(ctx, r.DB, r.Replica).Query(ctx, query, productID, tenantID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m StockMovement
+		if err := rows.Scan(&m.ID, &m.ProductID, &m.QuantityChange, &m.QuarantinedChange, &m.Reason, &m.ReferenceID, &m.Note, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		movements = append(movements, m)
+	}
+	return movements, rows.Err()
+}
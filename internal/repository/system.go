@@ -0,0 +1,14 @@
+package repository
+
+import "github.com/jackc/pgx/v5/pgxpool"
+
+// systemDB picks the pool for queries that must cross tenants before (or
+// without) knowing the organization. The tenant policies fail closed, so the
+// system pool connects as a role with BYPASSRLS; when none is configured the
+// primary is used and such queries only see rows if its role bypasses RLS.
+func systemDB(primary, system *pgxpool.Pool) *pgxpool.Pool {
+	if system != nil {
+		return system
+	}
+	return primary
+}
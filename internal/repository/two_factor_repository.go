@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const recoveryCodeCount = 10
+
+// TwoFactor is a user's TOTP state. Secret is set by setup but only enforced at
+// login once EnabledAt is set.
+type TwoFactor struct {
+	Secret    string
+	EnabledAt *time.Time
+}
+
+func (t TwoFactor) Enabled() bool {
+	return t.EnabledAt != nil
+}
+
+type TwoFactorRepository struct {
+	DB *pgxpool.Pool
+}
+
+func (r *TwoFactorRepository) GetTwoFactor(ctx context.Context, userID string) (TwoFactor, error) {
+	var t TwoFactor
+	var secret *string
+
+	query := `SELECT totp_secret, totp_enabled_at FROM users WHERE id = $1`
+	if err := conn(ctx, r.DB).QueryRow(ctx, query, userID).Scan(&secret, &t.EnabledAt); err != nil {
+		return t, fmt.Errorf("failed query two-factor: %w", err)
+	}
+	if secret != nil {
+		t.Secret = *secret
+	}
+	return t, nil
+}
+
+// SetPendingSecret stores a new secret that is not enforced until EnableTwoFactor.
+func (r *TwoFactorRepository) SetPendingSecret(ctx context.Context, userID, secret string) error {
+	query := `UPDATE users SET totp_secret = $1, totp_last_step = NULL WHERE id = $2 AND totp_enabled_at IS NULL`
+
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, query, secret, userID)
+	if err != nil {
+		return fmt.Errorf("failed store secret: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("two-factor already enabled")
+	}
+	return nil
+}
+
+// EnableTwoFactor turns on 2FA after the first code (at step) was verified and
+// returns a fresh set of plaintext recovery codes to show the user once.
+func (r *TwoFactorRepository) EnableTwoFactor(ctx context.Context, userID string, step int64) ([]string, error) {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE users SET totp_enabled_at = CURRENT_TIMESTAMP, totp_last_step = $1
+		WHERE id = $2 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+	`
+	commandTag, err := tx.Exec(ctx, query, step, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed enable two-factor: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return nil, fmt.Errorf("two-factor already enabled")
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, tx.Commit(ctx)
+}
+
+func (r *TwoFactorRepository) DisableTwoFactor(ctx context.Context, userID string) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL WHERE id = $1`
+	if _, err := tx.Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed disable two-factor: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed delete recovery codes: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RegenerateRecoveryCodes invalidates every existing recovery code and returns new ones.
+func (r *TwoFactorRepository) RegenerateRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, tx.Commit(ctx)
+}
+
+// UseTOTPStep records step as the last accepted code. It returns false when a
+// code from this or a later step was already used, i.e. a replay.
+func (r *TwoFactorRepository) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	query := `
+		UPDATE users SET totp_last_step = $1
+		WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)
+	`
+
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, query, step, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed record totp step: %w", err)
+	}
+	return commandTag.RowsAffected() == 1, nil
+}
+
+// UseRecoveryCode burns an unused recovery code, reporting whether it was valid.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID, code string) (bool, error) {
+	query := `
+		UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM user_recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+		)
+	`
+
+	commandTag, err := conn(ctx, r.DB).Exec(ctx, query, userID, hashRecoveryCode(code))
+	if err != nil {
+		return false, fmt.Errorf("failed use recovery code: %w", err)
+	}
+	return commandTag.RowsAffected() == 1, nil
+}
+
+// replaceRecoveryCodes generates codes of the form "xxxxx-xxxxx" and stores only
+// their SHA-256 hashes.
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string) ([]string, error) {
+	if _, err := tx.Exec(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return nil, fmt.Errorf("failed delete recovery codes: %w", err)
+	}
+
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed generate recovery code: %w", err)
+		}
+		s := strings.ToLower(enc.EncodeToString(b))[:10]
+		codes[i] = s[:5] + "-" + s[5:]
+
+		query := "INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)"
+		if _, err := tx.Exec(ctx, query, userID, hashRecoveryCode(codes[i])); err != nil {
+			return nil, fmt.Errorf("failed insert recovery code: %w", err)
+		}
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode normalizes case and dashes so users can type codes loosely.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"inventory-api/internal/logging"
+)
+
+// DBTX is the query surface shared by *pgxpool.Pool and pgx.Tx, so the same
+// statement runs on its own or as part of a unit of work.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type txKey struct{}
+
+// defaultTxAttempts is used when TxManager.MaxAttempts is zero.
+const defaultTxAttempts = 3
+
+// TxManager runs a unit of work spanning several repositories in one
+// transaction. Repositories pick the transaction up from the context, so their
+// methods keep the same signatures inside and outside WithTx.
+type TxManager struct {
+	DB *pgxpool.Pool
+	// IsoLevel defaults to the server's (read committed), where only deadlocks
+	// are retried; serialization failures need RepeatableRead or Serializable.
+	IsoLevel pgx.TxIsoLevel
+	// MaxAttempts bounds how often fn runs when the transaction keeps failing
+	// with a serialization failure or deadlock.
+	MaxAttempts int
+}
+
+// WithTx runs fn in a transaction, committing when fn returns nil and rolling
+// back otherwise; fn's error is returned as is. On a serialization failure
+// (40001) or deadlock (40P01) the whole transaction is retried, so fn must not
+// have side effects outside the database. A WithTx nested inside fn joins the
+// outer transaction.
+func (m *TxManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	attempts := m.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultTxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := m.runTx(ctx, fn)
+		if err == nil || attempt >= attempts || !isRetryableTxError(err) {
+			return err
+		}
+
+		// Short jittered pause so the conflicting transactions don't collide again
+		backoff := time.Duration(attempt)*20*time.Millisecond + rand.N(20*time.Millisecond)
+		logging.FromContext(ctx).Warn("Retrying transaction", "attempt", attempt, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (m *TxManager) runTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := m.DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: m.IsoLevel})
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed commit transaction: %w", err)
+	}
+	return nil
+}
+
+func isRetryableTxError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
+
+// conn returns the unit of work's transaction when ctx carries one, otherwise pool.
+func conn(ctx context.Context, pool *pgxpool.Pool) DBTX {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return pool
+}
+
+// begin starts a transaction on pool, or a savepoint inside the unit of work's
+// transaction when ctx carries one, so multi-statement methods can take part
+// in WithTx and still roll back only their own work.
+func begin(ctx context.Context, pool *pgxpool.Pool) (pgx.Tx, error) {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx.Begin(ctx)
+	}
+	return pool.Begin(ctx)
+}
@@ -2,37 +2,75 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Roles a user can hold within an organization
+const (
+	RoleAdmin = "admin"
+	RoleStaff = "staff"
+)
+
+// User token purposes stored in user_tokens.purpose
+const (
+	TokenEmailVerification = "email_verification"
+	TokenPasswordReset     = "password_reset"
+	// TokenMFAChallenge links the password step of a login to its second factor.
+	TokenMFAChallenge = "mfa_challenge"
+)
+
 type User struct {
 	ID       string `json:"id"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password,omitempty" validate:"required,min=6"`
+	// Role is the role in the organization the user is being added to.
+	Role            string     `json:"role,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
 }
 
 type UserRepository struct {
 	DB *pgxpool.Pool
 }
 
-func (r *UserRepository) CreateUser(ctx context.Context, u *User) error {
+// CreateUser stores the user and makes it a member of orgID with u.Role
+// (staff when empty).
+func (r *UserRepository) CreateUser(ctx context.Context, u *User, orgID string) error {
+	if u.Role == "" {
+		u.Role = RoleStaff
+	}
+
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `INSERT INTO users (email, password) VALUES ($1, $2) RETURNING id`
 
-	err := r.DB.QueryRow(ctx, query, u.Email, u.Password).Scan(&u.ID)
+	err = tx.QueryRow(ctx, query, u.Email, u.Password).Scan(&u.ID)
 
 	if err != nil {
 		return fmt.Errorf("failed register user: %w", err)
 	}
-	return nil
+
+	query = `INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, query, orgID, u.ID, u.Role); err != nil {
+		return fmt.Errorf("failed insert membership: %w", err)
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
-	query := `SELECT id, email, password FROM users WHERE email = $1`
+	query := `SELECT id, email, password, email_verified_at FROM users WHERE email = $1`
 
 	var u User
-	err := r.DB.QueryRow(ctx, query, email).Scan(&u.ID, &u.Email, &u.Password)
+	err := conn(ctx, r.DB).QueryRow(ctx, query, email).Scan(&u.ID, &u.Email, &u.Password, &u.EmailVerifiedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
@@ -40,3 +78,100 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*Use
 
 	return &u, nil
 }
+
+func (r *UserRepository) GetUserByID(ctx context.Context, id string) (*User, error) {
+	query := `SELECT id, email, password, email_verified_at FROM users WHERE id = $1`
+
+	var u User
+	err := conn(ctx, r.DB).QueryRow(ctx, query, id).Scan(&u.ID, &u.Email, &u.Password, &u.EmailVerifiedAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	return &u, nil
+}
+
+// CreateUserToken stores a single-use token row for the user and returns its ID.
+func (r *UserRepository) CreateUserToken(ctx context.Context, userID, purpose string, ttl time.Duration) (string, error) {
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, expires_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(secs => $3))
+		RETURNING id
+	`
+
+	var id string
+	if err := conn(ctx, r.DB).QueryRow(ctx, query, userID, purpose, ttl.Seconds()).Scan(&id); err != nil {
+		return "", fmt.Errorf("failed create user token: %w", err)
+	}
+	return id, nil
+}
+
+// ConsumeUserToken marks an unused, unexpired token as used and returns its user ID.
+func (r *UserRepository) ConsumeUserToken(ctx context.Context, id, purpose string) (string, error) {
+	query := `
+		UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING user_id
+	`
+
+	var userID string
+	if err := conn(ctx, r.DB).QueryRow(ctx, query, id, purpose).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("token invalid or expired")
+		}
+		return "", fmt.Errorf("failed consume user token: %w", err)
+	}
+	return userID, nil
+}
+
+// PeekUserToken returns the user ID of an unused, unexpired token without
+// consuming it, so a mistyped code does not void the whole login.
+func (r *UserRepository) PeekUserToken(ctx context.Context, id, purpose string) (string, error) {
+	query := `
+		SELECT user_id FROM user_tokens
+		WHERE id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	`
+
+	var userID string
+	if err := conn(ctx, r.DB).QueryRow(ctx, query, id, purpose).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("token invalid or expired")
+		}
+		return "", fmt.Errorf("failed query user token: %w", err)
+	}
+	return userID, nil
+}
+
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID string) error {
+	query := `UPDATE users SET email_verified_at = CURRENT_TIMESTAMP WHERE id = $1 AND email_verified_at IS NULL`
+
+	if _, err := conn(ctx, r.DB).Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed verify email: %w", err)
+	}
+	return nil
+}
+
+// UpdatePassword stores a new password hash and invalidates any outstanding reset tokens.
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	tx, err := begin(ctx, r.DB)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	commandTag, err := tx.Exec(ctx, `UPDATE users SET password = $1 WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed update password: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	query := `UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	if _, err := tx.Exec(ctx, query, userID, TokenPasswordReset); err != nil {
+		return fmt.Errorf("failed invalidate reset tokens: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
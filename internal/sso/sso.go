@@ -0,0 +1,143 @@
+// Package sso signs users in through an OpenID Connect provider with the
+// authorization-code flow (state, nonce and PKCE) and maps ID token claims to
+// an organization role.
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"slices"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// RoleClaim is the ID token claim holding the user's groups or roles,
+	// either a string or a list of strings.
+	RoleClaim string
+	// AdminValues are the RoleClaim values that map to admin; everyone else is staff.
+	AdminValues []string
+}
+
+// Identity is what a verified ID token says about the signed-in user.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Role          string
+}
+
+type Provider struct {
+	oauth       oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+	roleClaim   string
+	adminValues []string
+}
+
+// New fetches the provider's discovery document, so the provider must be
+// reachable at startup.
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed discover provider: %w", err)
+	}
+
+	return &Provider{
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier:    provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		roleClaim:   cfg.RoleClaim,
+		adminValues: cfg.AdminValues,
+	}, nil
+}
+
+// NewNonce returns a random value for the ID token nonce.
+func NewNonce() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed generate nonce: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NewVerifier returns a PKCE code verifier.
+func NewVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+// AuthCodeURL is where to send the browser to sign in.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange redeems the authorization code and verifies the returned ID token,
+// including that it carries the nonce of this login.
+func (p *Provider) Exchange(ctx context.Context, code, nonce, verifier string) (Identity, error) {
+	var identity Identity
+
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return identity, fmt.Errorf("failed exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return identity, fmt.Errorf("token response has no id_token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return identity, fmt.Errorf("failed verify id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return identity, fmt.Errorf("id_token nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return identity, fmt.Errorf("failed decode claims: %w", err)
+	}
+
+	identity.Issuer = idToken.Issuer
+	identity.Subject = idToken.Subject
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Role = p.mapRole(claims[p.roleClaim])
+	return identity, nil
+}
+
+// mapRole returns admin when the claim (a string or a list) contains one of
+// the admin values, and staff otherwise.
+func (p *Provider) mapRole(claim interface{}) string {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	for _, v := range values {
+		if slices.Contains(p.adminValues, v) {
+			return "admin"
+		}
+	}
+	return "staff"
+}
@@ -0,0 +1,38 @@
+package sso
+
+import "testing"
+
+func TestMapRole(t *testing.T) {
+	p := &Provider{adminValues: []string{"inventory-admins", "admin"}}
+
+	tests := []struct {
+		name  string
+		claim interface{}
+		want  string
+	}{
+		{"string admin value", "admin", "admin"},
+		{"string other value", "staff", "staff"},
+		{"list with admin value", []interface{}{"everyone", "inventory-admins"}, "admin"},
+		{"list without admin value", []interface{}{"everyone", "sales"}, "staff"},
+		{"list with non-string items", []interface{}{42, true, "admin"}, "admin"},
+		{"empty list", []interface{}{}, "staff"},
+		{"case sensitive", "Admin", "staff"},
+		{"missing claim", nil, "staff"},
+		{"unsupported type", map[string]interface{}{"role": "admin"}, "staff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.mapRole(tt.claim); got != tt.want {
+				t.Errorf("mapRole(%v) = %q, want %q", tt.claim, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapRoleWithoutAdminValues(t *testing.T) {
+	p := &Provider{}
+	if got := p.mapRole("admin"); got != "staff" {
+		t.Errorf("mapRole = %q, want staff when no admin values are configured", got)
+	}
+}
@@ -0,0 +1,41 @@
+package tokens
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Signer turns a stored token ID into an opaque "<id>.<signature>" string and
+// back. The signature stops clients from guessing or tampering with IDs; expiry
+// and single use are enforced by the database row the ID points to.
+type Signer struct {
+	Secret []byte
+}
+
+func (s *Signer) Sign(id string) string {
+	return id + "." + s.signature(id)
+}
+
+// Verify checks the signature and returns the token ID.
+func (s *Signer) Verify(token string) (string, error) {
+	id, sig, ok := strings.Cut(token, ".")
+	if !ok || id == "" {
+		return "", ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(s.signature(id))) {
+		return "", ErrInvalidToken
+	}
+	return id, nil
+}
+
+func (s *Signer) signature(id string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
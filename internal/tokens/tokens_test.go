@@ -0,0 +1,52 @@
+package tokens
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSignerRoundTrip(t *testing.T) {
+	s := &Signer{Secret: []byte("test-secret")}
+	id := "6f1c2b9e-8a41-4d55-9b7e-0c3f4a2d1e77"
+
+	token := s.Sign(id)
+	if !strings.HasPrefix(token, id+".") {
+		t.Fatalf("Sign(%q) = %q, want it to start with the ID", id, token)
+	}
+
+	got, err := s.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got != id {
+		t.Errorf("Verify = %q, want %q", got, id)
+	}
+}
+
+func TestSignerVerifyRejects(t *testing.T) {
+	s := &Signer{Secret: []byte("test-secret")}
+	other := &Signer{Secret: []byte("other-secret")}
+	valid := s.Sign("abc")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"no separator", "abc"},
+		{"empty id", "." + strings.SplitN(valid, ".", 2)[1]},
+		{"empty signature", "abc."},
+		{"tampered id", "abd." + strings.SplitN(valid, ".", 2)[1]},
+		{"tampered signature", valid + "x"},
+		{"signed with another secret", other.Sign("abc")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Verify(tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Verify(%q) error = %v, want ErrInvalidToken", tt.token, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 SHA-1 test key "12345678901234567890", base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// Appendix B vectors, truncated from 8 to 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeAcceptsLowercaseAndPadding(t *testing.T) {
+	want, _ := Code(rfcSecret, 1)
+	for _, secret := range []string{"gezdgnbvgy3tqojqgezdgnbvgy3tqojq", rfcSecret + "===="} {
+		got, err := Code(secret, 1)
+		if err != nil || got != want {
+			t.Errorf("Code(%q) = %q, %v; want %q", secret, got, err, want)
+		}
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("expected an error for an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+	code := func(s int64) string {
+		c, err := Code(rfcSecret, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfcSecret, code(step), 1, step, true},
+		{"previous step within skew", rfcSecret, code(step - 1), 1, step - 1, true},
+		{"next step within skew", rfcSecret, code(step + 1), 1, step + 1, true},
+		{"outside skew", rfcSecret, code(step - 2), 1, 0, false},
+		{"no skew", rfcSecret, code(step - 1), 0, 0, false},
+		{"wrong code", rfcSecret, "000000", 1, 0, false},
+		{"wrong length", rfcSecret, "12345", 1, 0, false},
+		{"invalid secret", "not base32!", "123456", 1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOK := Validate(tt.secret, tt.code, now, tt.skew)
+			if gotOK != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("Validate() = (%d, %v), want (%d, %v)", gotStep, gotOK, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Package tracing sets up OpenTelemetry: W3C trace context propagation, an
+// OTLP/HTTP exporter and per-route span names for chi.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"inventory-api/internal/logging"
+)
+
+// Exporters accepted by Setup
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+)
+
+// Setup installs the global propagator and, unless exporter is ExporterNone,
+// a tracer provider that batches spans to an OTLP/HTTP collector. The
+// collector address and headers come from the standard OTEL_EXPORTER_OTLP_*
+// variables (http://localhost:4318 by default). The returned function flushes
+// pending spans and must be called on shutdown.
+func Setup(ctx context.Context, exporter, serviceName string) (func(context.Context) error, error) {
+	// Incoming traceparent headers are honoured even when spans are not exported
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	switch exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+
+	exp, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed create OTLP exporter: %w", err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(semconv.ServiceName(serviceName)),
+	)
+	if err == nil {
+		res, err = resource.Merge(res, resource.Environment())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed build resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Middleware names the server span after the chi route pattern once routing
+// is done, so /products/{id} is one operation, and adds the trace ID to the
+// request logger. The span itself is started by otelhttp around the router.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		if sc := span.SpanContext(); sc.IsValid() {
+			r = r.WithContext(logging.With(r.Context(), "trace_id", sc.TraceID().String()))
+		}
+
+		next.ServeHTTP(w, r)
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route := rctx.RoutePattern()
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+	})
+}